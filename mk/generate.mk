@@ -12,8 +12,19 @@ generate-grafana-%:
 .PHONY: generate-grafana
 generate-grafana: $(JSONNET_BUNDLER_CACHE_DIR) $(addprefix generate-grafana-,$(dashboards))
 
+CRDS_DIR=pkg/graph/generators/k8s/validate/crds
+
+# CRDs of the custom resources the generators emit, embedded by the offline validator of manifests
+.PHONY: generate-crds
+generate-crds:
+	install -m 0644 $$($(GO) list -m -f '{{.Dir}}' github.com/kumahq/kuma/v2)/deployments/charts/kuma/crds/kuma.io_*.yaml $(CRDS_DIR)
+	install -m 0644 \
+		$$($(GO) list -m -f '{{.Dir}}' sigs.k8s.io/gateway-api)/config/crd/standard/gateway.networking.k8s.io_httproutes.yaml \
+		$$($(GO) list -m -f '{{.Dir}}' sigs.k8s.io/gateway-api)/config/crd/standard/gateway.networking.k8s.io_grpcroutes.yaml \
+		$(CRDS_DIR)
+
 .PHONY: generate
-generate: generate-grafana
+generate: generate-grafana generate-crds
 
 clean-%: 
 	rm -rf $(DASHBOARDS_DIR)/$*.json
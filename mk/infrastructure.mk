@@ -139,5 +139,10 @@ ecr/push/fake-service:
 	docker tag nicholasjackson/fake-service:v0.26.0 $(CONTAINER_REGISTRY)/fake-service:v0.26.0 && \
 	docker push $(CONTAINER_REGISTRY)/fake-service:v0.26.0
 
+.PHONY: ecr/push/mesh-perf-workload
+ecr/push/mesh-perf-workload:
+	docker buildx build --platform linux/arm64 --file tools/workload/Dockerfile \
+		--tag $(CONTAINER_REGISTRY)/mesh-perf-workload:latest --push $(TOP)
+
 .PHONY: ecr/push
-ecr/push: ecr/authenticate ecr/push/kuma-dp ecr/push/fake-service
+ecr/push: ecr/authenticate ecr/push/kuma-dp ecr/push/fake-service ecr/push/mesh-perf-workload
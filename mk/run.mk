@@ -10,6 +10,8 @@ E2E_ENV_VARS += PERF_LIMIT_MILLI_CPU=$${PERF_LIMIT_MILLI_CPU:=1000}
 E2E_ENV_VARS += PERF_TEST_NUM_SERVICES=$${PERF_TEST_NUM_SERVICES:=70}
 E2E_ENV_VARS += PERF_TEST_INSTANCES_PER_SERVICE=$${PERF_TEST_INSTANCES_PER_SERVICE:=2}
 E2E_ENV_VARS += PERF_TEST_STABILIZATION_SLEEP=$${PERF_TEST_STABILIZATION_SLEEP:=30s}
+# the load generator needs the workload image in CONTAINER_REGISTRY, it's off with 0
+E2E_ENV_VARS += PERF_TEST_LOAD_GENERATOR_RPS=$${PERF_TEST_LOAD_GENERATOR_RPS:=0}
 E2E_ENV_VARS += CONTAINER_REGISTRY=$(CONTAINER_REGISTRY)
 
 .PHONY: fetch-mesh
@@ -23,8 +25,24 @@ test-runs:
 
 .PHONY: run
 run: fetch-mesh | test-runs
-	$(E2E_ENV_VARS) $(GINKGO) --timeout=4h --label-filter="!limits" --json-report=raw-report.json -v ./test/... 2>&1;
+	$(E2E_ENV_VARS) $(GINKGO) --timeout=4h --label-filter="!limits && !policies && !routes && !external && !mixed" --json-report=raw-report.json -v ./test/... 2>&1;
 
 .PHONY: run/limits
 run/limits: fetch-mesh
 	$(E2E_ENV_VARS) $(GINKGO) -v --timeout=4h --label-filter="limits" --json-report=raw-report.json ./test/...
+
+.PHONY: run/policies
+run/policies: fetch-mesh
+	$(E2E_ENV_VARS) $(GINKGO) -v --timeout=4h --label-filter="policies" --json-report=raw-report.json ./test/...
+
+.PHONY: run/routes
+run/routes: fetch-mesh
+	$(E2E_ENV_VARS) $(GINKGO) -v --timeout=4h --label-filter="routes" --json-report=raw-report.json ./test/...
+
+.PHONY: run/external
+run/external: fetch-mesh
+	$(E2E_ENV_VARS) $(GINKGO) -v --timeout=4h --label-filter="external" --json-report=raw-report.json ./test/...
+
+.PHONY: run/mixed
+run/mixed: fetch-mesh
+	$(E2E_ENV_VARS) $(GINKGO) -v --timeout=4h --label-filter="mixed" --json-report=raw-report.json ./test/...
@@ -12,6 +12,12 @@ type Service struct {
 	Idx      int   `yaml:"idx" json:"idx"`
 	Edges    []int `yaml:"edges" json:"edges"`
 	Replicas int   `yaml:"replicas" json:"replicas"`
+	// External services run outside the mesh, they can only be leaves of the graph.
+	External bool `yaml:"external,omitempty" json:"external,omitempty"`
+	// Namespace the service runs in, generators use their own namespace when it's empty.
+	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	// Zone the service runs in, for graphs spread across the zones of a multizone deployment.
+	Zone string `yaml:"zone,omitempty" json:"zone,omitempty"`
 }
 
 type ServiceGraph struct {
@@ -30,6 +36,9 @@ func (g ServiceGraph) Validate() error {
 				return fmt.Errorf("service's Idx:%d has edge '%d' that is not an actual service", i, edge)
 			}
 		}
+		if srv.External && len(srv.Edges) != 0 {
+			return fmt.Errorf("service's Idx:%d is external but has edges", i)
+		}
 	}
 	// Check for cycles
 	permanentMark := map[int]struct{}{}
@@ -64,6 +73,34 @@ func (g ServiceGraph) Validate() error {
 	return nil
 }
 
+// Roots returns the indexes of the services no other service calls, the entry points of the graph.
+func (g ServiceGraph) Roots() []int {
+	called := map[int]struct{}{}
+	for _, srv := range g.Services {
+		for _, edge := range srv.Edges {
+			called[edge] = struct{}{}
+		}
+	}
+	var roots []int
+	for _, srv := range g.Services {
+		if _, ok := called[srv.Idx]; !ok {
+			roots = append(roots, srv.Idx)
+		}
+	}
+	return roots
+}
+
+// Leaves returns the indexes of the services that don't call any other service.
+func (g ServiceGraph) Leaves() []int {
+	var leaves []int
+	for _, srv := range g.Services {
+		if len(srv.Edges) == 0 {
+			leaves = append(leaves, srv.Idx)
+		}
+	}
+	return leaves
+}
+
 // Generator generates the graph is a custom format
 type Generator interface {
 	Apply(writer io.Writer, svc ServiceGraph) error
@@ -68,6 +68,16 @@ func TestValidate(t *testing.T) {
 			},
 			then: errors.New("service's Idx:0 has edge '1' that is not an actual service"),
 		},
+		{
+			desc: "External service with edges",
+			given: apis.ServiceGraph{
+				Services: []apis.Service{
+					{Idx: 0, Edges: []int{1}, Replicas: 2, External: true},
+					{Idx: 1, Edges: []int{}, Replicas: 2},
+				},
+			},
+			then: errors.New("service's Idx:0 is external but has edges"),
+		},
 	}
 	for _, tc := range tests {
 		got := tc.given.Validate()
@@ -76,3 +86,21 @@ func TestValidate(t *testing.T) {
 		}
 	}
 }
+
+func TestRootsAndLeaves(t *testing.T) {
+	given := apis.ServiceGraph{
+		Services: []apis.Service{
+			{Idx: 0, Edges: []int{2}, Replicas: 1},
+			{Idx: 1, Edges: []int{2, 3}, Replicas: 1},
+			{Idx: 2, Edges: []int{3}, Replicas: 1},
+			{Idx: 3, Edges: []int{}, Replicas: 1},
+			{Idx: 4, Edges: []int{}, Replicas: 1},
+		},
+	}
+	if got := given.Roots(); !reflect.DeepEqual([]int{0, 1, 4}, got) {
+		t.Fatalf("expected roots: [0 1 4], got: %v", got)
+	}
+	if got := given.Leaves(); !reflect.DeepEqual([]int{3, 4}, got) {
+		t.Fatalf("expected leaves: [3 4], got: %v", got)
+	}
+}
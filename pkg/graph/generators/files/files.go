@@ -0,0 +1,34 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Files is a set of generated files indexed by their slash separated path relative to the output directory.
+type Files map[string][]byte
+
+// Names returns the sorted paths of all the files.
+func (f Files) Names() []string {
+	var names []string
+	for name := range f {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Write writes all the files under dir, creating intermediate directories when needed.
+func (f Files) Write(dir string) error {
+	for _, name := range f.Names() {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, f[name], 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package gateway
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/pkg/graph/generators/policies"
+)
+
+type Options struct {
+	name             string
+	namespace        string
+	systemNamespace  string
+	mesh             string
+	formatters       k8s.Formatters
+	port             int
+	servicePort      int
+	replicas         int
+	serviceType      string
+	routes           int
+	skipGatewaySetup bool
+}
+
+type OptionFn func(Options) Options
+
+// WithName sets the name of the MeshGateway and MeshGatewayInstance.
+func WithName(name string) OptionFn {
+	return func(o Options) Options {
+		o.name = name
+		return o
+	}
+}
+
+// WithNamespace sets the namespace of the MeshGatewayInstance and of the services of the graph.
+func WithNamespace(name string) OptionFn {
+	return func(o Options) Options {
+		o.namespace = name
+		return o
+	}
+}
+
+// WithSystemNamespace sets the namespace of the control plane, where the routes are created.
+func WithSystemNamespace(name string) OptionFn {
+	return func(o Options) Options {
+		o.systemNamespace = name
+		return o
+	}
+}
+
+func WithMesh(name string) OptionFn {
+	return func(o Options) Options {
+		o.mesh = name
+		return o
+	}
+}
+
+// WithFormatters sets the formatters used to name services, they must match the ones of the workloads.
+func WithFormatters(f k8s.Formatters) OptionFn {
+	return func(o Options) Options {
+		o.formatters = f
+		return o
+	}
+}
+
+// WithPort sets the port of the gateway's HTTP listener.
+func WithPort(port int) OptionFn {
+	return func(o Options) Options {
+		o.port = port
+		return o
+	}
+}
+
+// WithServicePort sets the port the services of the graph listen on.
+func WithServicePort(port int) OptionFn {
+	return func(o Options) Options {
+		o.servicePort = port
+		return o
+	}
+}
+
+func WithReplicas(replicas int) OptionFn {
+	return func(o Options) Options {
+		o.replicas = replicas
+		return o
+	}
+}
+
+// WithServiceType sets the type of the Service fronting the gateway, ClusterIP by default.
+func WithServiceType(serviceType string) OptionFn {
+	return func(o Options) Options {
+		o.serviceType = serviceType
+		return o
+	}
+}
+
+// WithRoutes routes only the first n roots of the graph, so routes can be added in steps.
+func WithRoutes(n int) OptionFn {
+	return func(o Options) Options {
+		o.routes = n
+		return o
+	}
+}
+
+// SkipGatewaySetup generates only the routes, for a gateway that already exists.
+func SkipGatewaySetup() OptionFn {
+	return func(o Options) Options {
+		o.skipGatewaySetup = true
+		return o
+	}
+}
+
+// NewGenerator returns a generator of a builtin gateway with a MeshHTTPRoute to every root of the graph,
+// each root is exposed on the path prefix of its name.
+func NewGenerator(fns ...OptionFn) k8s.Generator {
+	opts := Options{
+		name:            "edge-gateway",
+		systemNamespace: k8s.DefaultSystemNamespace,
+		mesh:            "default",
+		formatters:      k8s.SimpleFormatters("microservice"),
+		port:            8080,
+		servicePort:     9090,
+		replicas:        1,
+		serviceType:     "ClusterIP",
+		routes:          -1,
+	}
+	for _, fn := range fns {
+		if fn != nil {
+			opts = fn(opts)
+		}
+	}
+	return k8s.Generator{
+		Serializer:  k8s.DefaultSerializer,
+		CommonSetup: k8s.CommonSetupFn(opts.generate),
+	}
+}
+
+// serviceTag is the kuma.io/service tag of the proxies of the gateway.
+func (o Options) serviceTag() string {
+	return fmt.Sprintf("%s_%s_svc", o.name, o.namespace)
+}
+
+func (o Options) generate(svcs apis.ServiceGraph) ([]runtime.Object, []byte, error) {
+	var out []runtime.Object
+	if !o.skipGatewaySetup {
+		instance, err := policies.New("MeshGatewayInstance", o.name, o.namespace, o.mesh, map[string]interface{}{
+			"replicas":    o.replicas,
+			"serviceType": o.serviceType,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		gateway, err := policies.New("MeshGateway", o.name, "", "", map[string]interface{}{
+			"selectors": []map[string]interface{}{
+				{"match": map[string]string{"kuma.io/service": o.serviceTag()}},
+			},
+			"conf": map[string]interface{}{
+				"listeners": []map[string]interface{}{
+					{
+						"port":     o.port,
+						"protocol": "HTTP",
+						"tags":     map[string]string{"port": fmt.Sprintf("http-%d", o.port)},
+					},
+				},
+			},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		gateway.Object["mesh"] = o.mesh
+		out = append(out, instance, gateway)
+	}
+	roots := svcs.Roots()
+	if o.routes >= 0 && o.routes < len(roots) {
+		roots = roots[:o.routes]
+	}
+	for _, idx := range roots {
+		name := o.formatters.Name(idx)
+		route, err := policies.New("MeshHTTPRoute", fmt.Sprintf("%s-%s", o.name, name), o.systemNamespace, o.mesh, policies.Spec{
+			TargetRef: &policies.TargetRef{Kind: "MeshGateway", Name: o.name},
+			To: []policies.To{
+				{
+					TargetRef: policies.TargetRef{Kind: "Mesh"},
+					Rules: []policies.Conf{
+						{
+							"matches": []map[string]interface{}{
+								{"path": map[string]string{"type": "PathPrefix", "value": fmt.Sprintf("/%s", name)}},
+							},
+							"default": map[string]interface{}{
+								"backendRefs": []map[string]interface{}{
+									{
+										"kind":      "MeshService",
+										"name":      name,
+										"namespace": o.namespace,
+										"port":      o.servicePort,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		out = append(out, route)
+	}
+	return out, nil, nil
+}
@@ -0,0 +1,72 @@
+package gateway_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/gateway"
+)
+
+func TestGenerator(t *testing.T) {
+	graph := apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{2}, Idx: 0},
+			{Replicas: 1, Edges: []int{2}, Idx: 1},
+			{Replicas: 1, Edges: []int{}, Idx: 2},
+		},
+	}
+	type testCase struct {
+		desc     string
+		opts     []gateway.OptionFn
+		expected map[string]int
+		contains []string
+	}
+	tests := []testCase{
+		{
+			desc: "routes to every root",
+			opts: []gateway.OptionFn{gateway.WithNamespace("foo")},
+			expected: map[string]int{
+				"kind: MeshGatewayInstance": 1,
+				"\nkind: MeshGateway\n":     1,
+				"kind: MeshHTTPRoute":       2,
+			},
+			contains: []string{
+				"kuma.io/service: edge-gateway_foo_svc",
+				"name: edge-gateway-microservice-001",
+				"value: /microservice-000",
+				"mesh: default",
+			},
+		},
+		{
+			desc: "routes only",
+			opts: []gateway.OptionFn{gateway.SkipGatewaySetup(), gateway.WithRoutes(1)},
+			expected: map[string]int{
+				"kind: MeshGatewayInstance": 0,
+				"\nkind: MeshGateway\n":     0,
+				"kind: MeshHTTPRoute":       1,
+			},
+			contains: []string{
+				"name: edge-gateway-microservice-000",
+			},
+		},
+	}
+	for _, tc := range tests {
+		buf := bytes.Buffer{}
+		if err := gateway.NewGenerator(tc.opts...).Apply(&buf, graph); err != nil {
+			t.Fatalf("test: %s, failed: %v", tc.desc, err)
+		}
+		out := buf.String()
+		for s, count := range tc.expected {
+			if got := strings.Count(out, s); got != count {
+				t.Errorf("test: %s, expected %d of %q, got: %d", tc.desc, count, s, got)
+			}
+		}
+		for _, s := range tc.contains {
+			if !strings.Contains(out, s) {
+				t.Errorf("test: %s, expected output to contain %q, got:\n%s", tc.desc, s, out)
+			}
+		}
+	}
+}
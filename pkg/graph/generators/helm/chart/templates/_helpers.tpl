@@ -0,0 +1,64 @@
+{{/*
+Name of a service, expects a list of the root context and the index of the service.
+*/}}
+{{- define "mesh-perf.name" -}}
+{{- printf "%s-%03d" (index . 0).Values.baseName (int (index . 1)) }}
+{{- end }}
+
+{{/*
+Metadata shared by all objects of a service, expects a list of the root context and the name of the service.
+*/}}
+{{- define "mesh-perf.metadata" -}}
+{{- $ := index . 0 }}
+{{- $name := index . 1 -}}
+labels:
+  app: {{ $name }}
+name: {{ $name }}
+{{- with $.Values.namespace }}
+namespace: {{ . }}
+{{- end }}
+{{- end }}
+
+{{/*
+Comma separated urls of the upstreams of a service, expects a list of the root context and the service.
+*/}}
+{{- define "mesh-perf.upstreamUris" -}}
+{{- $ := index . 0 }}
+{{- range $i, $edge := (index . 1).edges }}
+{{- if $i }},{{ end }}
+{{- printf "http://%s:%d" (include "mesh-perf.name" (list $ $edge)) (int $.Values.port) }}
+{{- end }}
+{{- end }}
+
+{{/*
+Value of the kuma.io/reachable-backends annotation, expects a list of the root context and the service.
+*/}}
+{{- define "mesh-perf.reachableBackends" -}}
+{{- $ := index . 0 }}
+{{- $refs := list }}
+{{- range $edge := (index . 1).edges }}
+{{- $refs = append $refs (dict "kind" "MeshService" "name" (include "mesh-perf.name" (list $ $edge)) "namespace" $.Values.namespace) }}
+{{- end }}
+{{- if $refs }}
+{{- dict "refs" $refs | toJson }}
+{{- else }}
+{{- "{}" }}
+{{- end }}
+{{- end }}
+
+{{/*
+Value of the kuma.io/transparent-proxying-reachable-services annotation, expects a list of the root context and
+the service. A service without edges reaches only itself.
+*/}}
+{{- define "mesh-perf.reachableServices" -}}
+{{- $ := index . 0 }}
+{{- $svc := index . 1 }}
+{{- $edges := $svc.edges }}
+{{- if not $edges }}
+{{- $edges = list $svc.idx }}
+{{- end }}
+{{- range $i, $edge := $edges }}
+{{- if $i }},{{ end }}
+{{- printf "%s_%s_svc_%d" (include "mesh-perf.name" (list $ $edge)) $.Values.namespace (int $.Values.port) }}
+{{- end }}
+{{- end }}
@@ -0,0 +1,156 @@
+package helm
+
+import (
+	"embed"
+	"io/fs"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/files"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+)
+
+//go:embed all:chart
+var chart embed.FS
+
+// Values are the values of the generated chart. With the same options the chart renders
+// byte-identical manifests to graph_k8s.NewGenerator with fakeservice.GeneratorOpts.
+type Values struct {
+	Namespace         string                  `json:"namespace"`
+	CreateNamespace   bool                    `json:"createNamespace"`
+	BaseName          string                  `json:"baseName"`
+	Image             Image                   `json:"image"`
+	Port              int                     `json:"port"`
+	Resources         v1.ResourceRequirements `json:"resources"`
+	ReachableBackends bool                    `json:"reachableBackends"`
+	ReachableServices bool                    `json:"reachableServices"`
+	Graph             apis.ServiceGraph       `json:"graph"`
+}
+
+type Image struct {
+	Registry   string `json:"registry"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+}
+
+type OptionFn func(Values) Values
+
+func WithNamespace(name string) OptionFn {
+	return func(v Values) Values {
+		v.Namespace = name
+		return v
+	}
+}
+
+func SkipNamespaceCreation() OptionFn {
+	return func(v Values) Values {
+		v.CreateNamespace = false
+		return v
+	}
+}
+
+func WithRegistry(imageRegistry string) OptionFn {
+	return func(v Values) Values {
+		if imageRegistry != "" {
+			v.Image.Registry = imageRegistry
+		}
+		return v
+	}
+}
+
+func WithPort(port int) OptionFn {
+	return func(v Values) Values {
+		v.Port = port
+		return v
+	}
+}
+
+func WithResources(resources v1.ResourceRequirements) OptionFn {
+	return func(v Values) Values {
+		v.Resources = resources
+		return v
+	}
+}
+
+func WithReachableBackends() OptionFn {
+	return func(v Values) Values {
+		v.ReachableBackends = true
+		return v
+	}
+}
+
+// WithReachableServices sets the reachable services of the pods, like fakeservice.WithReachableServices it's
+// ignored with WithReachableBackends.
+func WithReachableServices() OptionFn {
+	return func(v Values) Values {
+		v.ReachableServices = true
+		return v
+	}
+}
+
+// Generator generates a Helm chart deploying a service graph with fake-service.
+type Generator struct {
+	Values Values
+}
+
+func NewGenerator(fns ...OptionFn) Generator {
+	values := Values{
+		CreateNamespace: true,
+		BaseName:        "fake-service",
+		Image: Image{
+			Registry:   "nicholasjackson",
+			Repository: "fake-service",
+			Tag:        "v0.26.0",
+		},
+		Port:      9090,
+		Resources: k8s.DefaultResourceProfile().App,
+	}
+	for _, fn := range fns {
+		if fn != nil {
+			values = fn(values)
+		}
+	}
+	return Generator{Values: values}
+}
+
+// ApplyDir writes the chart of the graph to dir.
+func (g Generator) ApplyDir(dir string, svc apis.ServiceGraph) error {
+	out, err := g.Files(svc)
+	if err != nil {
+		return err
+	}
+	return out.Write(dir)
+}
+
+// Files generates the chart with the graph in its values.yaml.
+func (g Generator) Files(svc apis.ServiceGraph) (files.Files, error) {
+	root, err := fs.Sub(chart, "chart")
+	if err != nil {
+		return nil, err
+	}
+	out := files.Files{}
+	if err := fs.WalkDir(root, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(root, p)
+		if err != nil {
+			return err
+		}
+		out[p] = content
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	values := g.Values
+	values.Graph = svc
+	b, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	out["values.yaml"] = b
+	return out, nil
+}
@@ -0,0 +1,129 @@
+package helm_test
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/files"
+	"github.com/kong/mesh-perf/pkg/graph/generators/helm"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/fakeservice"
+)
+
+func TestRenderMatchesK8sGenerator(t *testing.T) {
+	graph := apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 2, Edges: []int{1, 2}, Idx: 0},
+			{Replicas: 2, Edges: []int{2}, Idx: 1},
+			{Replicas: 2, Edges: []int{3}, Idx: 2},
+			{Replicas: 2, Edges: []int{}, Idx: 3},
+		},
+	}
+	type testCase struct {
+		desc     string
+		helmOpts []helm.OptionFn
+		k8sOpts  []k8s.Option
+	}
+	tests := []testCase{
+		{
+			desc:     "defaults",
+			helmOpts: []helm.OptionFn{helm.WithNamespace("foo")},
+			k8sOpts:  append(fakeservice.GeneratorOpts(), k8s.WithNamespace("foo")),
+		},
+		{
+			desc:     "reachable backends and registry",
+			helmOpts: []helm.OptionFn{helm.WithNamespace("foo"), helm.WithReachableBackends(), helm.WithRegistry("registry.local")},
+			k8sOpts: append(
+				fakeservice.GeneratorOpts(fakeservice.WithReachableBackends(), fakeservice.WithRegistry("registry.local")),
+				k8s.WithNamespace("foo"),
+			),
+		},
+		{
+			desc:     "reachable services",
+			helmOpts: []helm.OptionFn{helm.WithNamespace("foo"), helm.WithReachableServices()},
+			k8sOpts:  append(fakeservice.GeneratorOpts(fakeservice.WithReachableServices()), k8s.WithNamespace("foo")),
+		},
+		{
+			desc:     "skip namespace creation",
+			helmOpts: []helm.OptionFn{helm.WithNamespace("foo"), helm.SkipNamespaceCreation()},
+			k8sOpts:  append(fakeservice.GeneratorOpts(), k8s.WithNamespace("foo"), k8s.SkipNamespaceCreation()),
+		},
+	}
+	for _, tc := range tests {
+		generator, err := k8s.NewGenerator(tc.k8sOpts...)
+		if err != nil {
+			t.Fatalf("test: %s, failed creating generator: %v", tc.desc, err)
+		}
+		expected := bytes.Buffer{}
+		if err := generator.Apply(&expected, graph); err != nil {
+			t.Fatalf("test: %s, failed generating: %v", tc.desc, err)
+		}
+
+		chart, err := helm.NewGenerator(tc.helmOpts...).Files(graph)
+		if err != nil {
+			t.Fatalf("test: %s, failed generating chart: %v", tc.desc, err)
+		}
+		got, err := render(chart)
+		if err != nil {
+			t.Fatalf("test: %s, failed rendering chart: %v", tc.desc, err)
+		}
+		if got := withoutSources(got); got != expected.String() {
+			t.Fatalf("test: %s, rendered chart differs, expected:\n%s\ngot:\n%s", tc.desc, expected.String(), got)
+		}
+	}
+}
+
+// render renders the chart with the engine of Helm. Outputs are concatenated in the order of template file names.
+func render(chartFiles files.Files) (string, error) {
+	var buffered []*loader.BufferedFile
+	for _, name := range chartFiles.Names() {
+		buffered = append(buffered, &loader.BufferedFile{Name: name, Data: chartFiles[name]})
+	}
+	c, err := loader.LoadFiles(buffered)
+	if err != nil {
+		return "", err
+	}
+	values, err := chartutil.ToRenderValues(
+		c,
+		c.Values,
+		chartutil.ReleaseOptions{Name: "graph", Namespace: "default", IsInstall: true},
+		chartutil.DefaultCapabilities,
+	)
+	if err != nil {
+		return "", err
+	}
+	rendered, err := engine.Render(c, values)
+	if err != nil {
+		return "", err
+	}
+	names := slices.Sorted(maps.Keys(rendered))
+	out := strings.Builder{}
+	for _, name := range names {
+		if strings.TrimSpace(rendered[name]) == "" {
+			continue
+		}
+		out.WriteString(rendered[name])
+	}
+	return out.String(), nil
+}
+
+// withoutSources drops the `# Source:` comments `helm template` prefixes each document with, which the k8s generator
+// doesn't emit.
+func withoutSources(s string) string {
+	var lines []string
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if strings.HasPrefix(line, "# Source: ") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "")
+}
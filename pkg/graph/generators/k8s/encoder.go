@@ -2,6 +2,7 @@ package k8s
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 
@@ -16,38 +17,84 @@ type Generator struct {
 	CommonSetup       CommonSetup
 	WorkloadGenerator WorkloadGenerator
 	Serializer        *json.Serializer
+	// AggregateErrors generates everything before writing anything, so a failure doesn't leave a half-written
+	// output. The errors of the common setup and of all the failing services are joined instead of stopping at
+	// the first one.
+	AggregateErrors bool
 }
 
 var DefaultSerializer = json.NewSerializerWithOptions(json.DefaultMetaFactory, nil, nil, json.SerializerOptions{Yaml: true, Pretty: true, Strict: true})
 
 func (e Generator) Apply(writer io.Writer, svc apis.ServiceGraph) error {
+	if !e.AggregateErrors {
+		return e.write(writer, svc)
+	}
+	b := bytes.Buffer{}
+	if err := e.write(&b, svc); err != nil {
+		return err
+	}
+	_, err := b.WriteTo(writer)
+	return err
+}
+
+func (e Generator) write(writer io.Writer, svc apis.ServiceGraph) error {
+	var errs []error
 	if e.CommonSetup != nil {
-		objs, raw, err := e.CommonSetup.Generate(svc)
-		if err != nil {
-			return err
-		}
-		if _, err := writer.Write(raw); err != nil {
-			return err
-		}
-		if err := e.encode(writer, objs...); err != nil {
-			return err
+		if err := e.writeCommonSetup(writer, svc); err != nil {
+			if !e.AggregateErrors {
+				return err
+			}
+			errs = append(errs, err)
 		}
 	}
+	if e.WorkloadGenerator == nil {
+		return errors.Join(errs...)
+	}
+	workloadGenerator := e.workloadGenerator(svc)
 	for _, s := range svc.Services {
-		objs, raw, err := e.WorkloadGenerator.Apply(s)
-		if err != nil {
-			return &ServiceGeneratorError{idx: s.Idx, err: err}
-		}
-		if _, err := writer.Write(raw); err != nil {
-			return err
-		}
-		if err := e.encode(writer, objs...); err != nil {
-			return &ServiceGeneratorError{idx: s.Idx, err: err}
+		if err := e.writeService(writer, workloadGenerator, s); err != nil {
+			if !e.AggregateErrors {
+				return err
+			}
+			errs = append(errs, err)
 		}
 	}
+	return errors.Join(errs...)
+}
+
+func (e Generator) writeCommonSetup(writer io.Writer, svc apis.ServiceGraph) error {
+	objs, raw, err := e.CommonSetup.Generate(svc)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(raw); err != nil {
+		return err
+	}
+	return e.encode(writer, objs...)
+}
+
+func (e Generator) writeService(writer io.Writer, workloadGenerator WorkloadGenerator, s apis.Service) error {
+	objs, raw, err := workloadGenerator.Apply(s)
+	if err != nil {
+		return &ServiceGeneratorError{idx: s.Idx, err: err}
+	}
+	if _, err := writer.Write(raw); err != nil {
+		return err
+	}
+	if err := e.encode(writer, objs...); err != nil {
+		return &ServiceGeneratorError{idx: s.Idx, err: err}
+	}
 	return nil
 }
 
+// workloadGenerator binds the WorkloadGenerator to the graph when it needs it.
+func (e Generator) workloadGenerator(svc apis.ServiceGraph) WorkloadGenerator {
+	if g, ok := e.WorkloadGenerator.(GraphWorkloadGenerator); ok {
+		return g.ForGraph(svc)
+	}
+	return e.WorkloadGenerator
+}
+
 func (e Generator) encode(writer io.Writer, inputs ...runtime.Object) error {
 	for _, in := range inputs {
 		_, err := writer.Write([]byte("---\n"))
@@ -100,6 +147,13 @@ type WorkloadGenerator interface {
 	Apply(svc apis.Service) ([]runtime.Object, []byte, error)
 }
 
+// GraphWorkloadGenerator is a WorkloadGenerator that needs the whole graph to generate a service,
+// like to know which of its upstreams are external.
+type GraphWorkloadGenerator interface {
+	WorkloadGenerator
+	ForGraph(svcs apis.ServiceGraph) WorkloadGenerator
+}
+
 type WorkloadGeneratorFn func(svc apis.Service) ([]runtime.Object, []byte, error)
 
 func (f WorkloadGeneratorFn) Apply(svc apis.Service) ([]runtime.Object, []byte, error) {
@@ -111,6 +165,11 @@ type ServiceGeneratorError struct {
 	err error
 }
 
+// Idx is the index of the service that failed.
+func (s *ServiceGeneratorError) Idx() int {
+	return s.idx
+}
+
 func (s *ServiceGeneratorError) Unwrap() error {
 	return s.err
 }
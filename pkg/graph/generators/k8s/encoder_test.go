@@ -0,0 +1,78 @@
+package k8s_test
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+)
+
+var errCommonSetup = errors.New("common setup")
+
+func TestAggregateErrors(t *testing.T) {
+	graph := apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Idx: 0},
+			{Replicas: 1, Idx: 1},
+			{Replicas: 1, Idx: 2},
+			{Replicas: 1, Idx: 3},
+		},
+	}
+	generator := k8s.Generator{
+		Serializer: k8s.DefaultSerializer,
+		WorkloadGenerator: k8s.WorkloadGeneratorFn(func(svc apis.Service) ([]runtime.Object, []byte, error) {
+			if svc.Idx%2 == 1 {
+				return nil, nil, errors.New("odd service")
+			}
+			return []runtime.Object{&v1.ConfigMap{
+				TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+			}}, nil, nil
+		}),
+	}
+
+	buf := bytes.Buffer{}
+	err := generator.Apply(&buf, graph)
+	if !errors.Is(err, &k8s.ServiceGeneratorError{}) {
+		t.Fatalf("expected a ServiceGeneratorError, got: %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Error("expected the services before the failing one to be written")
+	}
+
+	generator.AggregateErrors = true
+	generator.CommonSetup = k8s.CommonSetupFn(func(apis.ServiceGraph) ([]runtime.Object, []byte, error) {
+		return nil, nil, errCommonSetup
+	})
+	buf.Reset()
+	err = generator.Apply(&buf, graph)
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be written, got:\n%s", buf.String())
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined errors, got: %v", err)
+	}
+	errs := joined.Unwrap()
+	if len(errs) == 0 || !errors.Is(errs[0], errCommonSetup) {
+		t.Fatalf("expected the error of the common setup first, got: %v", err)
+	}
+	var idxs []int
+	for _, err := range errs[1:] {
+		var svcErr *k8s.ServiceGeneratorError
+		if !errors.As(err, &svcErr) {
+			t.Fatalf("expected a ServiceGeneratorError, got: %v", err)
+		}
+		idxs = append(idxs, svcErr.Idx())
+	}
+	if !slices.Equal(idxs, []int{1, 3}) {
+		t.Errorf("expected failures of services [1 3], got: %v", idxs)
+	}
+}
@@ -0,0 +1,211 @@
+package k8s
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+)
+
+const (
+	// DefaultExternalNamespace is the namespace of the stand-ins of external services, it's outside the mesh.
+	DefaultExternalNamespace = "mesh-perf-external"
+
+	KumaSidecarInjectionLabel = "kuma.io/sidecar-injection"
+)
+
+// WithExternalNamespace sets the namespace of the stand-ins of external services.
+func WithExternalNamespace(name string) Option {
+	return OptionFn(func(g *generator) error {
+		g.externalNamespace = name
+		return nil
+	})
+}
+
+// WithMeshPassthrough makes services reach external services directly through a MeshPassthrough
+// matching their stand-in, instead of through a MeshExternalService.
+func WithMeshPassthrough() Option {
+	return OptionFn(func(g *generator) error {
+		g.meshPassthrough = true
+		return nil
+	})
+}
+
+// formattersForGraph returns the formatters given to mutators, when the generator is bound to a graph
+// they look up its services and give the urls of external services and of services in other namespaces and zones.
+func (g generator) formattersForGraph() Formatters {
+	if g.graph == nil {
+		return g.formatters
+	}
+	f := g.formatters
+	graph := *g.graph
+	url := f.Url
+	f.Service = func(idx int) apis.Service {
+		return graph.Services[idx]
+	}
+	f.Url = func(idx int, port int) string {
+		svc := graph.Services[idx]
+		if svc.External {
+			return fmt.Sprintf("http://%s:%d", g.externalHost(f.Name(idx)), port)
+		}
+		if f.ServiceUrl != nil {
+			return f.ServiceUrl(svc, port)
+		}
+		return url(idx, port)
+	}
+	return f
+}
+
+// externalHost is the hostname services call an external service on.
+func (g generator) externalHost(name string) string {
+	if g.meshPassthrough {
+		return g.standInHost(name)
+	}
+	// the hostname of the default HostnameGenerator of MeshExternalServices
+	return fmt.Sprintf("%s.extsvc.mesh.local", name)
+}
+
+func (g generator) standInHost(name string) string {
+	return fmt.Sprintf("%s.%s.svc.cluster.local", name, g.externalNamespace)
+}
+
+// external generates an external service: a stand-in outside the mesh serving it locally, and the
+// MeshExternalService or MeshPassthrough the mesh reaches it through. A MeshExternalService is reached through
+// the ZoneEgress, which the mesh must have, and with mTLS only when a MeshTrafficPermission allows it.
+func (g generator) external(svc apis.Service) ([]runtime.Object, []byte, error) {
+	// stand-ins serve only the api port, over http
+	g.extraPorts = nil
+	g.appProtocol = nil
+	name := g.formatters.Name(svc.Idx)
+	objectMeta := metav1.ObjectMeta{
+		Name:      name,
+		Namespace: g.externalNamespace,
+		Labels: map[string]string{
+			"app": name,
+		},
+	}
+	podTemplateSpec := v1.PodTemplateSpec{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name:            "app",
+					Image:           g.image,
+					ImagePullPolicy: v1.PullAlways,
+					ReadinessProbe: &v1.Probe{
+						InitialDelaySeconds: 3,
+						ProbeHandler: v1.ProbeHandler{
+							HTTPGet: &v1.HTTPGetAction{
+								Port: intstr.FromInt32(g.port),
+								Path: "/ready",
+							},
+						},
+					},
+					Resources: g.resourceProfile(svc).App,
+				},
+			},
+		},
+	}
+	objectMeta.DeepCopyInto(&podTemplateSpec.ObjectMeta)
+	podTemplateSpec.Labels[KumaSidecarInjectionLabel] = "disabled"
+	g.placement(svc).apply(&podTemplateSpec)
+
+	// stand-ins serve all the time, whatever the kind of the workloads of the graph
+	out := g.workload(Deployment, objectMeta, svc.Replicas, podTemplateSpec)
+	out = append(out, g.service(svc, objectMeta))
+	if g.meshPassthrough {
+		out = append(out, g.passthrough(name))
+	} else {
+		out = append(out, g.meshExternalService(name), g.externalTrafficPermission(name))
+	}
+	return out, nil, nil
+}
+
+func (g generator) meshExternalService(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "kuma.io/v1alpha1",
+			"kind":       "MeshExternalService",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": g.systemNamespace,
+			},
+			"spec": map[string]interface{}{
+				"match": map[string]interface{}{
+					"type":     "HostnameGenerator",
+					"port":     int64(g.port),
+					"protocol": "http",
+				},
+				"endpoints": []interface{}{
+					map[string]interface{}{
+						"address": g.standInHost(name),
+						"port":    int64(g.port),
+					},
+				},
+			},
+		},
+	}
+}
+
+// externalTrafficPermission allows the services of the mesh to reach the MeshExternalService.
+func (g generator) externalTrafficPermission(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "kuma.io/v1alpha1",
+			"kind":       "MeshTrafficPermission",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": g.systemNamespace,
+			},
+			"spec": map[string]interface{}{
+				"targetRef": map[string]interface{}{
+					"kind": "MeshExternalService",
+					"name": name,
+				},
+				"from": []interface{}{
+					map[string]interface{}{
+						"targetRef": map[string]interface{}{
+							"kind": "Mesh",
+						},
+						"default": map[string]interface{}{
+							"action": "Allow",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (g generator) passthrough(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "kuma.io/v1alpha1",
+			"kind":       "MeshPassthrough",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": g.systemNamespace,
+			},
+			"spec": map[string]interface{}{
+				"targetRef": map[string]interface{}{
+					"kind": "Mesh",
+				},
+				"default": map[string]interface{}{
+					"passthroughMode": "Matched",
+					"appendMatch": []interface{}{
+						map[string]interface{}{
+							"type":     "Domain",
+							"value":    g.standInHost(name),
+							"port":     int64(g.port),
+							"protocol": "http",
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,104 @@
+package fakeservice
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/pkg/workload"
+)
+
+// ConfigFile is the path of the config file of WithConfigFile, in the ConfigMap mounted by k8s.WithConfigMapGenerator.
+const ConfigFile = "/etc/config/config.yaml"
+
+// WithConfigFile moves the upstreams, profile and TLS of each service from the environment of its pods to a
+// config file in its ConfigMap, so they're changed by updating the ConfigMaps without restarting the pods.
+// fake-service only reads its environment, so the services run WorkloadImageName instead, the workload of
+// workloadapp, which reads and reloads the config file. It only serves HTTP, like with WithHTTPOnly.
+func WithConfigFile() OptionFn {
+	return func(o Options) Options {
+		o.configFile = true
+		o.imageName = WorkloadImageName
+		o.httpOnly = true
+		return o
+	}
+}
+
+// WithTLS makes the services serve TLS with the certificate and key files at the paths, which a
+// k8s.PodTemplateSpecMutator mounts in the pods. The mesh only sees TCP traffic between them.
+func WithTLS(certFile string, keyFile string) OptionFn {
+	return func(o Options) Options {
+		o.tlsCertFile = certFile
+		o.tlsKeyFile = keyFile
+		return o
+	}
+}
+
+// WithTLSCA makes the services verify their https upstreams with the CA file at the path, mounted like the
+// files of WithTLS. Only the workload of workloadapp reads it.
+func WithTLSCA(caFile string) OptionFn {
+	return func(o Options) Options {
+		o.tlsCAFile = caFile
+		return o
+	}
+}
+
+// configEnv are the variables configuring the service, in the environment of its pods or in its config file.
+func (o Options) configEnv(formatters k8s.Formatters, svc apis.Service) ([]v1.EnvVar, error) {
+	var uris []string
+	for _, v := range svc.Edges {
+		uris = append(uris, o.upstreamUrl(formatters, v))
+	}
+	env := []v1.EnvVar{{Name: workload.UpstreamURIsEnv, Value: strings.Join(uris, ",")}}
+	if o.profile != nil {
+		profile := o.profile(svc)
+		if err := profile.validate(); err != nil {
+			return nil, fmt.Errorf("invalid profile of %s: %w", formatters.Name(svc.Idx), err)
+		}
+		env = append(env, profile.env()...)
+	}
+	if o.tlsCertFile != "" {
+		env = append(env,
+			v1.EnvVar{Name: workload.TLSCertEnv, Value: o.tlsCertFile},
+			v1.EnvVar{Name: workload.TLSKeyEnv, Value: o.tlsKeyFile},
+		)
+	}
+	if o.tlsCAFile != "" {
+		env = append(env, v1.EnvVar{Name: workload.TLSCAEnv, Value: o.tlsCAFile})
+	}
+	return env, nil
+}
+
+// renderConfigFile renders the config file of a service, the variables of its config by name.
+func renderConfigFile(opts Options) func(k8s.Formatters, apis.Service) (string, error) {
+	return func(formatters k8s.Formatters, svc apis.Service) (string, error) {
+		env, err := opts.configEnv(formatters, svc)
+		if err != nil {
+			return "", err
+		}
+		vars := map[string]string{}
+		for _, e := range env {
+			vars[e.Name] = e.Value
+		}
+		out, err := yaml.Marshal(vars)
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	}
+}
+
+// configureTLS probes the pods over HTTPS.
+func configureTLS(_ k8s.Formatters, _ apis.Service, template *v1.PodTemplateSpec) error {
+	container := &template.Spec.Containers[0]
+	for _, probe := range []*v1.Probe{container.LivenessProbe, container.ReadinessProbe} {
+		if probe != nil && probe.HTTPGet != nil {
+			probe.HTTPGet.Scheme = v1.URISchemeHTTPS
+		}
+	}
+	return nil
+}
@@ -14,14 +14,37 @@ import (
 
 	"github.com/kong/mesh-perf/pkg/graph/apis"
 	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/pkg/workload"
 )
 
 var Formatters = k8s.SimpleFormatters("fake-service")
 
+// Port is the port fake services listen on.
+const Port = 9090
+
+// ImageName is the image of fake-service in the registry.
+const ImageName = "fake-service:v0.26.0"
+
+// DefaultRegistry is the registry of fake-service when WithRegistry isn't set.
+const DefaultRegistry = "nicholasjackson"
+
+// WorkloadImageName is the image of the workload of tools/workload, which reads the config of fake-service.
+const WorkloadImageName = "mesh-perf-workload:latest"
+
 type Options struct {
+	systemNamespace      string
 	imageRegistry        string
+	imageName            string
 	useReachableBackends bool
 	useReachableServices bool
+	extraPorts           []k8s.ServicePort
+	profile              func(svc apis.Service) Profile
+	protocol             func(svc apis.Service) Protocol
+	httpOnly             bool
+	configFile           bool
+	tlsCertFile          string
+	tlsKeyFile           string
+	tlsCAFile            string
 }
 
 type OptionFn func(Options) Options
@@ -35,6 +58,23 @@ func WithRegistry(imageRegistry string) OptionFn {
 	}
 }
 
+// WithImageName runs another image reading the config of fake-service, like the workload of pkg/workload.
+// It's pulled from the registry of WithRegistry, or without one from the default registry of the container runtime.
+func WithImageName(name string) OptionFn {
+	return func(o Options) Options {
+		o.imageName = name
+		return o
+	}
+}
+
+// WithSystemNamespace sets the namespace of the control plane, where the MeshExternalServices of external services are.
+func WithSystemNamespace(name string) OptionFn {
+	return func(o Options) Options {
+		o.systemNamespace = name
+		return o
+	}
+}
+
 func WithReachableBackends() OptionFn {
 	return func(o Options) Options {
 		o.useReachableBackends = true
@@ -49,9 +89,19 @@ func WithReachableServices() OptionFn {
 	}
 }
 
+// WithExtraPorts adds ports to the Services of fake services, they only listen on Port so the extra ports
+// shape the config of the mesh without carrying traffic. The reachable backends then target Port.
+func WithExtraPorts(ports ...k8s.ServicePort) OptionFn {
+	return func(o Options) Options {
+		o.extraPorts = ports
+		return o
+	}
+}
+
 func GeneratorOpts(fns ...OptionFn) []k8s.Option {
 	opts := Options{
-		imageRegistry: "nicholasjackson",
+		systemNamespace: k8s.DefaultSystemNamespace,
+		imageName:       ImageName,
 	}
 
 	for _, fn := range fns {
@@ -60,16 +110,42 @@ func GeneratorOpts(fns ...OptionFn) []k8s.Option {
 		}
 	}
 
-	return []k8s.Option{
-		k8s.WithPort(9090),
+	appProtocol := func(svc apis.Service) string {
+		if opts.tlsCertFile != "" {
+			return "tcp"
+		}
+		return string(opts.protocolOf(svc))
+	}
+
+	k8sOpts := []k8s.Option{
+		k8s.WithPort(Port),
 		k8s.WithFormatters(Formatters),
-		k8s.WithImage(fmt.Sprintf("%s/fake-service:v0.26.0", opts.imageRegistry)),
+		k8s.WithImage(opts.image()),
+		k8s.WithExtraPorts(opts.extraPorts...),
+		k8s.WithAppProtocolFn(appProtocol),
 		k8s.WithPodTemplateSpecMutators(
-			mutatePodTemplate,
+			mutatePodTemplate(opts),
+			mutateMaybe(opts.protocol != nil, configureGRPC(opts)),
 			mutateMaybe(opts.useReachableServices && !opts.useReachableBackends, configureReachableServices),
-			mutateMaybe(opts.useReachableBackends, configureReachableBackends),
+			mutateMaybe(opts.useReachableBackends, configureReachableBackends(opts.systemNamespace, len(opts.extraPorts) > 0)),
+			mutateMaybe(opts.tlsCertFile != "", configureTLS),
 		),
 	}
+	if opts.configFile {
+		k8sOpts = append(k8sOpts, k8s.WithConfigMapGenerator(renderConfigFile(opts)))
+	}
+	return k8sOpts
+}
+
+func (o Options) image() string {
+	registry := o.imageRegistry
+	if registry == "" && o.imageName == ImageName {
+		registry = DefaultRegistry
+	}
+	if registry == "" {
+		return o.imageName
+	}
+	return fmt.Sprintf("%s/%s", registry, o.imageName)
 }
 
 func mutateMaybe(predicate bool, fn k8s.PodTemplateSpecMutator) k8s.PodTemplateSpecMutator {
@@ -80,58 +156,84 @@ func mutateMaybe(predicate bool, fn k8s.PodTemplateSpecMutator) k8s.PodTemplateS
 	return fn
 }
 
-func mutatePodTemplate(formatters k8s.Formatters, svc apis.Service, template *v1.PodTemplateSpec) error {
-	var uris []string
-	for _, v := range svc.Edges {
-		uris = append(uris, formatters.Url(v, 9090))
-	}
-	template.Spec.Containers[0].Env = append(template.Spec.Containers[0].Env,
-		v1.EnvVar{
+func mutatePodTemplate(opts Options) k8s.PodTemplateSpecMutator {
+	return func(formatters k8s.Formatters, svc apis.Service, template *v1.PodTemplateSpec) error {
+		container := &template.Spec.Containers[0]
+		container.Env = append(container.Env, v1.EnvVar{
 			Name:  "SERVICE",
 			Value: formatters.Name(svc.Idx),
-		},
-		v1.EnvVar{
-			Name:  "UPSTREAM_URIS",
-			Value: strings.Join(uris, ","),
-		},
-	)
-	return nil
+		})
+		if opts.configFile {
+			container.Env = append(container.Env, v1.EnvVar{Name: workload.ConfigFileEnv, Value: ConfigFile})
+			return nil
+		}
+		env, err := opts.configEnv(formatters, svc)
+		if err != nil {
+			return err
+		}
+		container.Env = append(container.Env, env...)
+		return nil
+	}
 }
 
-func configureReachableBackends(formatters k8s.Formatters, svc apis.Service, template *v1.PodTemplateSpec) error {
-	var refs controllers.ReachableBackendRefs
+// configureReachableBackends makes the edges of the service reachable, only on Port when targetPort is set.
+func configureReachableBackends(systemNamespace string, targetPort bool) k8s.PodTemplateSpecMutator {
+	return func(formatters k8s.Formatters, svc apis.Service, template *v1.PodTemplateSpec) error {
+		var refs controllers.ReachableBackendRefs
 
-	for _, v := range svc.Edges {
-		refs.Refs = append(refs.Refs, &controllers.ReachableBackendRef{
-			Kind:      string(v1alpha1.MeshService),
-			Name:      pointer.To(formatters.Name(v)),
-			Namespace: &template.Namespace,
-		})
-	}
+		for _, v := range svc.Edges {
+			ref := &controllers.ReachableBackendRef{
+				Kind:      string(v1alpha1.MeshService),
+				Name:      pointer.To(formatters.Name(v)),
+				Namespace: pointer.To(formatters.Namespace(v, template.Namespace)),
+			}
+			if formatters.External(v) {
+				ref.Kind = string(v1alpha1.MeshExternalService)
+				ref.Namespace = pointer.To(systemNamespace)
+			} else if targetPort {
+				ref.Port = pointer.To(uint32(Port))
+			}
+			// MeshServices synced from other zones have generated names, they're found by their labels
+			if zone := formatters.Zone(v); zone != "" && !formatters.External(v) {
+				ref.Labels = map[string]string{
+					"kuma.io/display-name":  *ref.Name,
+					"k8s.kuma.io/namespace": *ref.Namespace,
+					"kuma.io/zone":          zone,
+				}
+				ref.Name = nil
+				ref.Namespace = nil
+			}
+			refs.Refs = append(refs.Refs, ref)
+		}
 
-	refsAnnotationValue, err := json.Marshal(refs)
-	if err != nil {
-		return err
-	}
+		refsAnnotationValue, err := json.Marshal(refs)
+		if err != nil {
+			return err
+		}
 
-	if template.Annotations == nil {
-		template.Annotations = map[string]string{}
-	}
+		if template.Annotations == nil {
+			template.Annotations = map[string]string{}
+		}
 
-	template.Annotations[metadata.KumaReachableBackends] = string(refsAnnotationValue)
+		template.Annotations[metadata.KumaReachableBackends] = string(refsAnnotationValue)
 
-	return nil
+		return nil
+	}
 }
 
 func configureReachableServices(formatters k8s.Formatters, svc apis.Service, template *v1.PodTemplateSpec) error {
 	var names []string
 
 	for _, v := range svc.Edges {
+		// external services aren't services of the mesh, they're reached through a MeshExternalService or MeshPassthrough
+		if formatters.External(v) {
+			continue
+		}
 		names = append(names, fmt.Sprintf(
 			"%s_%s_svc_%d",
 			formatters.Name(v),
-			template.GetNamespace(),
-			9090,
+			formatters.Namespace(v, template.GetNamespace()),
+			Port,
 		))
 	}
 
@@ -144,7 +246,7 @@ func configureReachableServices(formatters k8s.Formatters, svc apis.Service, tem
 			"%s_%s_svc_%d",
 			formatters.Name(svc.Idx),
 			template.GetNamespace(),
-			9090,
+			Port,
 		))
 	}
 
@@ -2,7 +2,9 @@ package fakeservice_test
 
 import (
 	"bytes"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/kong/mesh-perf/pkg/graph/apis"
 	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
@@ -30,3 +32,215 @@ func TestSimple(t *testing.T) {
 	}
 	println(buf.String())
 }
+
+func TestExternalAndUnmeshed(t *testing.T) {
+	opts := fakeservice.GeneratorOpts(fakeservice.WithReachableBackends())
+	// a quarter of 4 services is the last one
+	opts = append(opts, k8s.WithNamespace("foo"), k8s.WithUnmeshed(25))
+	encoder, err := k8s.NewGenerator(opts...)
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = encoder.Apply(buf, apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1, 2}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1},
+			{Replicas: 1, Edges: []int{}, Idx: 2, External: true},
+			{Replicas: 1, Edges: []int{}, Idx: 3},
+		},
+	})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	out := buf.String()
+	for _, s := range []string{
+		"value: http://fake-service-001:9090,http://fake-service-002.extsvc.mesh.local:9090",
+		`{"kind":"MeshExternalService","name":"fake-service-002","namespace":"kong-mesh-system"}`,
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected output to contain %q, got:\n%s", s, out)
+		}
+	}
+	// the stand-in of the external service and the unmeshed service
+	if got := strings.Count(out, "kuma.io/sidecar-injection: disabled"); got != 2 {
+		t.Errorf("expected 2 pods without sidecar, got: %d", got)
+	}
+}
+
+func TestExtraPorts(t *testing.T) {
+	opts := fakeservice.GeneratorOpts(
+		fakeservice.WithReachableBackends(),
+		fakeservice.WithExtraPorts(k8s.ServicePort{Name: "admin", Port: 9000, AppProtocol: "grpc"}),
+	)
+	opts = append(opts, k8s.WithNamespace("foo"))
+	encoder, err := k8s.NewGenerator(opts...)
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = encoder.Apply(buf, apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	out := buf.String()
+	for _, s := range []string{
+		"value: http://fake-service-001:9090",
+		`{"kind":"MeshService","name":"fake-service-001","namespace":"foo","port":9090}`,
+		"name: admin",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected output to contain %q, got:\n%s", s, out)
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	graph := apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1},
+		},
+	}
+	generate := func(fns ...fakeservice.OptionFn) (string, error) {
+		encoder, err := k8s.NewGenerator(append(fakeservice.GeneratorOpts(fns...), k8s.WithNamespace("foo"))...)
+		if err != nil {
+			t.Fatal("failed", err)
+		}
+		buf := bytes.NewBuffer([]byte{})
+		err = encoder.Apply(buf, graph)
+		return buf.String(), err
+	}
+
+	out, err := generate(
+		fakeservice.WithProfile(fakeservice.Profile{Timing50: 20 * time.Millisecond, ErrorRate: 0.01, UpstreamWorkers: 2}),
+	)
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	for _, s := range []string{
+		"- name: TIMING_50_PERCENTILE\n          value: 20ms",
+		"- name: ERROR_RATE\n          value: \"0.01\"",
+		"- name: UPSTREAM_WORKERS\n          value: \"2\"",
+	} {
+		if got := strings.Count(out, s); got != 2 {
+			t.Errorf("expected both services to have %q, got %d in:\n%s", s, got, out)
+		}
+	}
+	if strings.Contains(out, "ERROR_CODE") {
+		t.Errorf("expected no ERROR_CODE without an error code, got:\n%s", out)
+	}
+
+	// leaves are slower
+	out, err = generate(fakeservice.WithProfileFn(func(svc apis.Service) fakeservice.Profile {
+		if len(svc.Edges) == 0 {
+			return fakeservice.Profile{Timing99: time.Second}
+		}
+		return fakeservice.Profile{}
+	}))
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	if got := strings.Count(out, "TIMING_99_PERCENTILE"); got != 1 {
+		t.Errorf("expected only the leaf to have a latency, got %d in:\n%s", got, out)
+	}
+
+	if _, err := generate(fakeservice.WithProfile(fakeservice.Profile{ErrorRate: 2})); err == nil {
+		t.Error("expected an error rate over 1 to fail")
+	}
+}
+
+func TestGRPC(t *testing.T) {
+	// only the leaf serves gRPC
+	opts := fakeservice.GeneratorOpts(
+		fakeservice.WithReachableBackends(),
+		fakeservice.WithProtocolFn(func(svc apis.Service) fakeservice.Protocol {
+			if len(svc.Edges) == 0 {
+				return fakeservice.GRPC
+			}
+			return fakeservice.HTTP
+		}),
+	)
+	opts = append(opts, k8s.WithNamespace("foo"))
+	encoder, err := k8s.NewGenerator(opts...)
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = encoder.Apply(buf, apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1, 2}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1},
+			{Replicas: 1, Edges: []int{}, Idx: 2, External: true},
+		},
+	})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	out := buf.String()
+	for s, count := range map[string]int{
+		"value: grpc://fake-service-001:9090,http://fake-service-002.extsvc.mesh.local:9090": 1,
+		"- name: SERVER_TYPE\n          value: grpc":                                         1,
+		"appProtocol: grpc":             1,
+		"appProtocol: http":             2,
+		"grpc:\n            port: 9090": 2,
+	} {
+		if got := strings.Count(out, s); got != count {
+			t.Errorf("expected output to contain %q %d times, got %d in:\n%s", s, count, got, out)
+		}
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	opts := fakeservice.GeneratorOpts(
+		fakeservice.WithRegistry("registry.local"),
+		fakeservice.WithConfigFile(),
+		fakeservice.WithProfile(fakeservice.Profile{ErrorRate: 0.5}),
+		fakeservice.WithTLS("/etc/tls/tls.crt", "/etc/tls/tls.key"),
+		fakeservice.WithTLSCA("/etc/tls/ca.crt"),
+	)
+	opts = append(opts, k8s.WithNamespace("foo"))
+	encoder, err := k8s.NewGenerator(opts...)
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = encoder.Apply(buf, apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	out := buf.String()
+	for s, count := range map[string]int{
+		"- name: CONFIG_FILE\n          value: /etc/config/config.yaml": 2,
+		"mountPath: /etc/config": 2,
+		"config.yaml: |\n    ERROR_RATE: \"0.5\"\n    TLS_CA_LOCATION: /etc/tls/ca.crt\n    TLS_CERT_LOCATION: /etc/tls/tls.crt\n    TLS_KEY_LOCATION: /etc/tls/tls.key\n    UPSTREAM_URIS: https://fake-service-001:9090\n": 1,
+		"image: registry.local/mesh-perf-workload:latest": 2,
+		"scheme: HTTPS":      4,
+		"appProtocol: tcp":   2,
+		"- name: ERROR_RATE": 0,
+	} {
+		if got := strings.Count(out, s); got != count {
+			t.Errorf("expected output to contain %q %d times, got %d in:\n%s", s, count, got, out)
+		}
+	}
+
+	// the workload reading the config file only serves HTTP
+	encoder, err = k8s.NewGenerator(fakeservice.GeneratorOpts(fakeservice.WithConfigFile(), fakeservice.WithGRPC())...)
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	err = encoder.Apply(&bytes.Buffer{}, apis.ServiceGraph{Services: []apis.Service{{Replicas: 1, Edges: []int{}, Idx: 0}}})
+	if err == nil || !strings.Contains(err.Error(), "only serves HTTP") {
+		t.Errorf("expected a gRPC service reading a config file to fail, got: %v", err)
+	}
+}
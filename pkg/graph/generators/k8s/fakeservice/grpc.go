@@ -0,0 +1,90 @@
+package fakeservice
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+)
+
+// Protocol is the protocol a fake service serves and is called on.
+type Protocol string
+
+const (
+	HTTP Protocol = "http"
+	GRPC Protocol = "grpc"
+)
+
+// WithGRPC makes every service serve gRPC, so the proxies get HTTP/2 listeners.
+func WithGRPC() OptionFn {
+	return WithProtocolFn(func(apis.Service) Protocol {
+		return GRPC
+	})
+}
+
+// WithHTTPOnly is for images that only serve HTTP, like the workload of workloadapp: generating a service of
+// another protocol fails.
+func WithHTTPOnly() OptionFn {
+	return func(o Options) Options {
+		o.httpOnly = true
+		return o
+	}
+}
+
+// WithProtocolFn sets the protocol of each service. External services are always called over HTTP, their
+// stand-ins serve HTTP.
+func WithProtocolFn(fn func(svc apis.Service) Protocol) OptionFn {
+	return func(o Options) Options {
+		o.protocol = fn
+		return o
+	}
+}
+
+func (o Options) protocolOf(svc apis.Service) Protocol {
+	if o.protocol == nil || svc.External {
+		return HTTP
+	}
+	return o.protocol(svc)
+}
+
+// upstreamUrl is the url of the Formatters of the upstream, with the scheme of its protocol.
+func (o Options) upstreamUrl(formatters k8s.Formatters, idx int) string {
+	url := formatters.Url(idx, Port)
+	svc := apis.Service{Idx: idx}
+	if formatters.Service != nil {
+		svc = formatters.Service(idx)
+	}
+	switch {
+	case o.protocolOf(svc) == GRPC:
+		return "grpc://" + strings.TrimPrefix(url, "http://")
+	case o.tlsCertFile != "" && !svc.External:
+		return "https://" + strings.TrimPrefix(url, "http://")
+	}
+	return url
+}
+
+// configureGRPC makes the pods of gRPC services serve gRPC, they're probed with the gRPC health checks of
+// fake-service as the health endpoints are only served over HTTP.
+func configureGRPC(opts Options) k8s.PodTemplateSpecMutator {
+	return func(formatters k8s.Formatters, svc apis.Service, template *v1.PodTemplateSpec) error {
+		if opts.protocolOf(svc) != GRPC {
+			return nil
+		}
+		if opts.httpOnly {
+			return fmt.Errorf("%s can't serve gRPC, its image only serves HTTP", formatters.Name(svc.Idx))
+		}
+		container := &template.Spec.Containers[0]
+		container.Env = append(container.Env, v1.EnvVar{Name: "SERVER_TYPE", Value: string(GRPC)})
+		for _, probe := range []*v1.Probe{container.LivenessProbe, container.ReadinessProbe} {
+			if probe != nil {
+				probe.ProbeHandler = v1.ProbeHandler{
+					GRPC: &v1.GRPCAction{Port: Port},
+				}
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package fakeservice
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/workload"
+)
+
+// Profile is the latency and error profile of a fake service, the zero values keep the defaults of fake-service.
+type Profile struct {
+	// Timing50, Timing90 and Timing99 are the latencies of the 50th, 90th and 99th percentile of requests.
+	Timing50 time.Duration
+	Timing90 time.Duration
+	Timing99 time.Duration
+	// ErrorRate is the ratio of requests, between 0 and 1, failing with ErrorCode.
+	ErrorRate float64
+	ErrorCode int
+	// UpstreamWorkers is the number of upstreams called concurrently.
+	UpstreamWorkers int
+	// UpstreamTimeout is the timeout of the calls to upstreams.
+	UpstreamTimeout time.Duration
+}
+
+// WithProfile gives every service the profile.
+func WithProfile(profile Profile) OptionFn {
+	return WithProfileFn(func(apis.Service) Profile {
+		return profile
+	})
+}
+
+// WithProfileFn gives each service the profile derived from it, like slower leaves or errors in one part of the graph.
+func WithProfileFn(fn func(svc apis.Service) Profile) OptionFn {
+	return func(o Options) Options {
+		o.profile = fn
+		return o
+	}
+}
+
+func (p Profile) validate() error {
+	for _, d := range []time.Duration{p.Timing50, p.Timing90, p.Timing99, p.UpstreamTimeout} {
+		if d < 0 {
+			return fmt.Errorf("durations can't be negative, got %s", d)
+		}
+	}
+	if p.ErrorRate < 0 || p.ErrorRate > 1 {
+		return fmt.Errorf("error rate must be between 0 and 1, got %v", p.ErrorRate)
+	}
+	if p.ErrorCode != 0 && (p.ErrorCode < 100 || p.ErrorCode > 599) {
+		return fmt.Errorf("error code must be an HTTP status code, got %d", p.ErrorCode)
+	}
+	if p.UpstreamWorkers < 0 {
+		return fmt.Errorf("upstream workers can't be negative, got %d", p.UpstreamWorkers)
+	}
+	return nil
+}
+
+func (p Profile) env() []v1.EnvVar {
+	var env []v1.EnvVar
+	add := func(name string, value string, set bool) {
+		if set {
+			env = append(env, v1.EnvVar{Name: name, Value: value})
+		}
+	}
+	add(workload.Timing50Env, p.Timing50.String(), p.Timing50 != 0)
+	add(workload.Timing90Env, p.Timing90.String(), p.Timing90 != 0)
+	add(workload.Timing99Env, p.Timing99.String(), p.Timing99 != 0)
+	add(workload.ErrorRateEnv, strconv.FormatFloat(p.ErrorRate, 'f', -1, 64), p.ErrorRate != 0)
+	add(workload.ErrorCodeEnv, strconv.Itoa(p.ErrorCode), p.ErrorCode != 0)
+	add(workload.UpstreamWorkersEnv, strconv.Itoa(p.UpstreamWorkers), p.UpstreamWorkers != 0)
+	add(workload.UpstreamTimeoutEnv, p.UpstreamTimeout.String(), p.UpstreamTimeout != 0)
+	return env
+}
@@ -0,0 +1,202 @@
+package gatewayapi
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+)
+
+// RouteKind is the kind of Gateway API route generated.
+type RouteKind string
+
+const (
+	HTTPRoute RouteKind = "HTTPRoute"
+	GRPCRoute RouteKind = "GRPCRoute"
+)
+
+// Scope decides whether a route is generated per service or per edge of the graph.
+type Scope string
+
+const (
+	// PerService generates a route for each service, for the traffic from all its callers. It's the default.
+	PerService Scope = "Service"
+	// PerEdge generates a route for each edge. Routes attached to a Service can't select the caller, so the routes
+	// of the edges to a service only differ by name: the first one changes the configuration of its callers and
+	// the others only add resources for the control plane to reconcile.
+	PerEdge Scope = "Edge"
+)
+
+type Options struct {
+	namespace  string
+	formatters k8s.Formatters
+	port       int
+	kind       RouteKind
+	scope      Scope
+	maxRoutes  int
+}
+
+type OptionFn func(Options) Options
+
+// WithNamespace sets the namespace of the services of the graph, where the routes are created.
+func WithNamespace(name string) OptionFn {
+	return func(o Options) Options {
+		o.namespace = name
+		return o
+	}
+}
+
+// WithFormatters sets the formatters used to name services, they must match the ones of the workloads.
+func WithFormatters(f k8s.Formatters) OptionFn {
+	return func(o Options) Options {
+		o.formatters = f
+		return o
+	}
+}
+
+// WithPort sets the port of the Services the routes are attached to.
+func WithPort(port int) OptionFn {
+	return func(o Options) Options {
+		o.port = port
+		return o
+	}
+}
+
+func WithRouteKind(kind RouteKind) OptionFn {
+	return func(o Options) Options {
+		o.kind = kind
+		return o
+	}
+}
+
+func WithScope(scope Scope) OptionFn {
+	return func(o Options) Options {
+		o.scope = scope
+		return o
+	}
+}
+
+// WithRoutes generates only the first n routes of the graph, so routes can be added in steps.
+func WithRoutes(n int) OptionFn {
+	return func(o Options) Options {
+		o.maxRoutes = n
+		return o
+	}
+}
+
+// Route is a route of the graph, to a service from a caller, or from all its callers when Caller is -1.
+type Route struct {
+	Caller  int
+	Service int
+}
+
+// NewGenerator returns a generator of GAMMA style Gateway API routes, attached to the Services of the graph.
+func NewGenerator(fns ...OptionFn) k8s.Generator {
+	opts := newOptions(fns...)
+	return k8s.Generator{
+		Serializer:  k8s.DefaultSerializer,
+		CommonSetup: k8s.CommonSetupFn(opts.generate),
+	}
+}
+
+// Routes returns the routes the generator with the same options generates for the graph, in order.
+func Routes(svcs apis.ServiceGraph, fns ...OptionFn) []Route {
+	return newOptions(fns...).routes(svcs)
+}
+
+func newOptions(fns ...OptionFn) Options {
+	opts := Options{
+		formatters: k8s.SimpleFormatters("microservice"),
+		port:       9090,
+		kind:       HTTPRoute,
+		scope:      PerService,
+		maxRoutes:  -1,
+	}
+	for _, fn := range fns {
+		if fn != nil {
+			opts = fn(opts)
+		}
+	}
+	return opts
+}
+
+func (o Options) routes(svcs apis.ServiceGraph) []Route {
+	var out []Route
+	for _, svc := range svcs.Services {
+		switch o.scope {
+		case PerEdge:
+			for _, edge := range svc.Edges {
+				out = append(out, Route{Caller: svc.Idx, Service: edge})
+			}
+		default:
+			out = append(out, Route{Caller: -1, Service: svc.Idx})
+		}
+	}
+	if o.maxRoutes >= 0 && o.maxRoutes < len(out) {
+		out = out[:o.maxRoutes]
+	}
+	return out
+}
+
+func (o Options) generate(svcs apis.ServiceGraph) ([]runtime.Object, []byte, error) {
+	var out []runtime.Object
+	for _, route := range o.routes(svcs) {
+		dst := route.Service
+		name := o.formatters.Name(dst)
+		if route.Caller >= 0 {
+			name = fmt.Sprintf("%s-to-%s", o.formatters.Name(route.Caller), name)
+		}
+		objectMeta := metav1.ObjectMeta{
+			Name:      name,
+			Namespace: o.namespace,
+		}
+		spec := gatewayv1.CommonRouteSpec{
+			ParentRefs: []gatewayv1.ParentReference{
+				{
+					Group: ptr.To(gatewayv1.Group("")),
+					Kind:  ptr.To(gatewayv1.Kind("Service")),
+					Name:  gatewayv1.ObjectName(o.formatters.Name(dst)),
+					Port:  ptr.To(gatewayv1.PortNumber(o.port)),
+				},
+			},
+		}
+		backendRef := gatewayv1.BackendRef{
+			BackendObjectReference: gatewayv1.BackendObjectReference{
+				Name: gatewayv1.ObjectName(o.formatters.Name(dst)),
+				Port: ptr.To(gatewayv1.PortNumber(o.port)),
+			},
+		}
+		switch o.kind {
+		case GRPCRoute:
+			rule := gatewayv1.GRPCRouteRule{
+				BackendRefs: []gatewayv1.GRPCBackendRef{{BackendRef: backendRef}},
+			}
+			out = append(out, &gatewayv1.GRPCRoute{
+				TypeMeta:   metav1.TypeMeta{Kind: string(GRPCRoute), APIVersion: gatewayv1.GroupVersion.String()},
+				ObjectMeta: objectMeta,
+				Spec: gatewayv1.GRPCRouteSpec{
+					CommonRouteSpec: spec,
+					Rules:           []gatewayv1.GRPCRouteRule{rule},
+				},
+			})
+		default:
+			rule := gatewayv1.HTTPRouteRule{
+				BackendRefs: []gatewayv1.HTTPBackendRef{{BackendRef: backendRef}},
+			}
+			out = append(out, &gatewayv1.HTTPRoute{
+				TypeMeta:   metav1.TypeMeta{Kind: string(HTTPRoute), APIVersion: gatewayv1.GroupVersion.String()},
+				ObjectMeta: objectMeta,
+				Spec: gatewayv1.HTTPRouteSpec{
+					CommonRouteSpec: spec,
+					Rules:           []gatewayv1.HTTPRouteRule{rule},
+				},
+			})
+		}
+	}
+	return out, nil, nil
+}
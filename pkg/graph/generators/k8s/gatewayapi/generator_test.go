@@ -0,0 +1,82 @@
+package gatewayapi_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/gatewayapi"
+)
+
+func TestGenerator(t *testing.T) {
+	graph := apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1, 2}, Idx: 0},
+			{Replicas: 1, Edges: []int{2}, Idx: 1},
+			{Replicas: 1, Edges: []int{}, Idx: 2},
+		},
+	}
+	type testCase struct {
+		desc     string
+		opts     []gatewayapi.OptionFn
+		expected map[string]int
+		contains []string
+	}
+	tests := []testCase{
+		{
+			desc: "http routes per edge",
+			opts: []gatewayapi.OptionFn{gatewayapi.WithNamespace("foo"), gatewayapi.WithScope(gatewayapi.PerEdge)},
+			expected: map[string]int{
+				"kind: HTTPRoute": 3,
+				"kind: Service":   3,
+				"matches":         0,
+			},
+			contains: []string{
+				"name: microservice-000-to-microservice-002",
+				"namespace: foo",
+			},
+		},
+		{
+			desc: "grpc routes per service",
+			opts: []gatewayapi.OptionFn{
+				gatewayapi.WithRouteKind(gatewayapi.GRPCRoute),
+				gatewayapi.WithRoutes(2),
+			},
+			expected: map[string]int{
+				"kind: GRPCRoute": 2,
+				"matches":         0,
+			},
+			contains: []string{
+				"name: microservice-001",
+			},
+		},
+	}
+	for _, tc := range tests {
+		buf := bytes.Buffer{}
+		if err := gatewayapi.NewGenerator(tc.opts...).Apply(&buf, graph); err != nil {
+			t.Fatalf("test: %s, failed: %v", tc.desc, err)
+		}
+		out := buf.String()
+		for s, count := range tc.expected {
+			if got := strings.Count(out, s); got != count {
+				t.Errorf("test: %s, expected %d of %q, got: %d", tc.desc, count, s, got)
+			}
+		}
+		for _, s := range tc.contains {
+			if !strings.Contains(out, s) {
+				t.Errorf("test: %s, expected output to contain %q, got:\n%s", tc.desc, s, out)
+			}
+		}
+	}
+
+	routes := gatewayapi.Routes(graph, gatewayapi.WithRoutes(2))
+	if expected := []gatewayapi.Route{{Caller: -1, Service: 0}, {Caller: -1, Service: 1}}; !reflect.DeepEqual(expected, routes) {
+		t.Errorf("expected routes: %v, got: %v", expected, routes)
+	}
+	routes = gatewayapi.Routes(graph, gatewayapi.WithScope(gatewayapi.PerEdge), gatewayapi.WithRoutes(2))
+	if expected := []gatewayapi.Route{{Caller: 0, Service: 1}, {Caller: 0, Service: 2}}; !reflect.DeepEqual(expected, routes) {
+		t.Errorf("expected routes: %v, got: %v", expected, routes)
+	}
+}
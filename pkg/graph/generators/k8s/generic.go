@@ -4,10 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"time"
 
-	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -23,7 +22,8 @@ type PodTemplateSpecMutator func(
 
 // Generator is a Kubernetes resource generator
 type generator struct {
-	asStatefulSet           bool
+	workloadKind            func(svc apis.Service) WorkloadKind
+	jobLifetime             time.Duration
 	namespace               string
 	image                   string
 	port                    int32
@@ -31,12 +31,54 @@ type generator struct {
 	configMapGenerator      func(formatters Formatters, svc apis.Service) (string, error)
 	podTemplateSpecMutators []PodTemplateSpecMutator
 	skipNamespaceCreation   bool
+	systemNamespace         string
+	resourceProfile         func(svc apis.Service) ResourceProfile
+	placement               func(svc apis.Service) Placement
+	labels                  []func(svc apis.Service) map[string]string
+	annotations             []func(svc apis.Service) map[string]string
+	extraPorts              []ServicePort
+	appProtocol             func(svc apis.Service) string
+	headlessServices        bool
+	aggregateErrors         bool
+	versions                []Version
+	externalNamespace       string
+	meshPassthrough         bool
+	zone                    string
+	hostnameGenerator       bool
+	loadGenerator           *LoadGenerator
+	unmeshedPercent         int
+	graph                   *apis.ServiceGraph
 }
 
 type Formatters struct {
 	BaseName string
 	Name     func(idx int) string
 	Url      func(idx int, port int) string
+	// Service looks up a service of the graph being generated, it's nil outside a generation.
+	Service func(idx int) apis.Service
+	// ServiceUrl gives the url of a service from its namespace and zone, Url uses it during a generation when it's set.
+	ServiceUrl func(svc apis.Service, port int) string
+}
+
+// External tells whether the service is external, so it's reached through the MeshExternalService or MeshPassthrough.
+func (f Formatters) External(idx int) bool {
+	return f.Service != nil && f.Service(idx).External
+}
+
+// Namespace is the namespace of the service during a generation, namespace otherwise or when it doesn't set one.
+func (f Formatters) Namespace(idx int, namespace string) string {
+	if f.Service == nil {
+		return namespace
+	}
+	return namespaceOr(f.Service(idx), namespace)
+}
+
+// Zone is the zone of the service during a generation, it's empty otherwise or when it doesn't set one.
+func (f Formatters) Zone(idx int) string {
+	if f.Service == nil {
+		return ""
+	}
+	return f.Service(idx).Zone
 }
 
 func SimpleFormatters(baseName string) Formatters {
@@ -80,6 +122,23 @@ func WithPodTemplateSpecMutators(fns ...PodTemplateSpecMutator) Option {
 	})
 }
 
+// WithUnmeshed runs percent of the services of the graph without a sidecar, spread evenly across the graph, like
+// a mesh in the middle of a migration. External services are left as they are.
+func WithUnmeshed(percent int) Option {
+	return OptionFn(func(g *generator) error {
+		if percent < 0 || percent > 100 {
+			return fmt.Errorf("invalid percent of unmeshed services %d", percent)
+		}
+		g.unmeshedPercent = percent
+		return nil
+	})
+}
+
+// Unmeshed tells whether the service runs without a sidecar with WithUnmeshed(percent).
+func Unmeshed(svc apis.Service, percent int) bool {
+	return !svc.External && (svc.Idx+1)*percent/100 > svc.Idx*percent/100
+}
+
 func WithPort(p int) Option {
 	return OptionFn(func(g *generator) error {
 		g.port = int32(p)
@@ -109,15 +168,20 @@ func WithNamespace(name string) Option {
 }
 
 func AsStatefulSet() Option {
+	return WithWorkloadKind(StatefulSet)
+}
+
+func SkipNamespaceCreation() Option {
 	return OptionFn(func(g *generator) error {
-		g.asStatefulSet = true
+		g.skipNamespaceCreation = true
 		return nil
 	})
 }
 
-func SkipNamespaceCreation() Option {
+// AggregateErrors makes the generator write nothing unless every service is generated, see Generator.AggregateErrors.
+func AggregateErrors() Option {
 	return OptionFn(func(g *generator) error {
-		g.skipNamespaceCreation = true
+		g.aggregateErrors = true
 		return nil
 	})
 }
@@ -127,7 +191,19 @@ func NewGenerator(opts ...Option) (Generator, error) {
 		Serializer: DefaultSerializer,
 	}
 	g := &generator{
-		formatters: SimpleFormatters("microservice"),
+		formatters:        SimpleFormatters("microservice"),
+		systemNamespace:   DefaultSystemNamespace,
+		externalNamespace: DefaultExternalNamespace,
+		resourceProfile: func(apis.Service) ResourceProfile {
+			return DefaultResourceProfile()
+		},
+		workloadKind: func(apis.Service) WorkloadKind {
+			return Deployment
+		},
+		jobLifetime: DefaultJobLifetime,
+		placement: func(apis.Service) Placement {
+			return Placement{}
+		},
 	}
 	for _, o := range opts {
 		if err := o.Apply(g); err != nil {
@@ -135,28 +211,54 @@ func NewGenerator(opts ...Option) (Generator, error) {
 		}
 	}
 	out.WorkloadGenerator = g
-	if !g.skipNamespaceCreation {
-		out.CommonSetup = commonSetup(g.namespace)
-	}
+	out.AggregateErrors = g.aggregateErrors
+	out.CommonSetup = CommonSetupFn(g.commonSetup)
 	return out, nil
 }
 
-func commonSetup(ns string) CommonSetupFn {
-	return func(svcs apis.ServiceGraph) ([]runtime.Object, []byte, error) {
-		ns := &v1.Namespace{
-			TypeMeta: metav1.TypeMeta{
-				APIVersion: "v1",
-				Kind:       "Namespace",
-			},
-			ObjectMeta: metav1.ObjectMeta{
-				Name: ns,
-			},
-			Spec: v1.NamespaceSpec{},
+func (g generator) commonSetup(svcs apis.ServiceGraph) ([]runtime.Object, []byte, error) {
+	var out []runtime.Object
+	if !g.skipNamespaceCreation {
+		out = append(out, namespace(g.namespace))
+		namespaces := map[string]struct{}{g.namespace: {}}
+		for _, svc := range svcs.Services {
+			if _, ok := namespaces[svc.Namespace]; ok || svc.Namespace == "" || svc.External || !g.inZone(svc) {
+				continue
+			}
+			namespaces[svc.Namespace] = struct{}{}
+			out = append(out, namespace(svc.Namespace))
 		}
-		return []runtime.Object{
-			ns,
-		}, nil, nil
 	}
+	// external services run outside the mesh in their own namespace
+	if slices.ContainsFunc(svcs.Services, func(svc apis.Service) bool { return svc.External }) {
+		out = append(out, namespace(g.externalNamespace))
+	}
+	if g.hostnameGenerator {
+		out = append(out, g.meshServiceHostnameGenerator())
+	}
+	if g.loadGenerator != nil {
+		out = append(out, g.loadGeneratorDeployment(svcs)...)
+	}
+	return out, nil, nil
+}
+
+func namespace(name string) *v1.Namespace {
+	return &v1.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1.NamespaceSpec{},
+	}
+}
+
+// ForGraph binds the generator to the graph, so the formatters given to mutators know the external services.
+func (g generator) ForGraph(svcs apis.ServiceGraph) WorkloadGenerator {
+	g.graph = &svcs
+	return g
 }
 
 func (g generator) Apply(svc apis.Service) ([]runtime.Object, []byte, error) {
@@ -166,15 +268,22 @@ func (g generator) Apply(svc apis.Service) ([]runtime.Object, []byte, error) {
 	if g.port < 0 || g.port > 65535 {
 		return nil, nil, errors.New("invalid port")
 	}
+	if !g.inZone(svc) {
+		return nil, nil, nil
+	}
+	if svc.External {
+		return g.external(svc)
+	}
+	formatters := g.formattersForGraph()
 	name := g.formatters.Name(svc.Idx)
 	baseObjectMeta := metav1.ObjectMeta{
-		Name:      name,
-		Namespace: g.namespace,
-		Labels: map[string]string{
-			"app": name,
-		},
+		Name:        name,
+		Namespace:   g.namespaceOf(svc),
+		Labels:      merged(svc, g.labels),
+		Annotations: merged(svc, g.annotations),
 	}
-	var workload runtime.Object
+	baseObjectMeta.Labels["app"] = name
+	profile := g.resourceProfile(svc)
 	podTemplateSpec := v1.PodTemplateSpec{
 		Spec: v1.PodSpec{
 			Volumes: []v1.Volume{},
@@ -202,15 +311,7 @@ func (g generator) Apply(svc apis.Service) ([]runtime.Object, []byte, error) {
 							},
 						},
 					},
-					Resources: v1.ResourceRequirements{
-						Limits: v1.ResourceList{
-							v1.ResourceMemory: resource.MustParse("32Mi"),
-						},
-						Requests: v1.ResourceList{
-							v1.ResourceMemory: resource.MustParse("32Mi"),
-							v1.ResourceCPU:    resource.MustParse("100m"),
-						},
-					},
+					Resources: profile.App,
 				},
 			},
 		},
@@ -232,90 +333,41 @@ func (g generator) Apply(svc apis.Service) ([]runtime.Object, []byte, error) {
 		})
 	}
 	baseObjectMeta.DeepCopyInto(&podTemplateSpec.ObjectMeta)
+	g.placement(svc).apply(&podTemplateSpec)
+	for k, v := range profile.sidecarAnnotations() {
+		podTemplateSpec.Annotations[k] = v
+	}
+	if Unmeshed(svc, g.unmeshedPercent) {
+		podTemplateSpec.Labels[KumaSidecarInjectionLabel] = "disabled"
+	}
 	if g.podTemplateSpecMutators != nil {
 		for _, mutator := range g.podTemplateSpecMutators {
-			if err := mutator(g.formatters, svc, &podTemplateSpec); err != nil {
+			if err := mutator(formatters, svc, &podTemplateSpec); err != nil {
 				return nil, nil, err
 			}
 		}
 	}
 
-	repl := int32(svc.Replicas)
-	if g.asStatefulSet {
-		sts := &appsv1.StatefulSet{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "StatefulSet",
-				APIVersion: "apps/v1",
-			},
-			Spec: appsv1.StatefulSetSpec{
-				ServiceName: name,
-				Replicas:    &repl,
-				Selector: &metav1.LabelSelector{
-					MatchLabels: map[string]string{
-						"app": name,
-					},
-				},
-				Template: podTemplateSpec,
-			},
-		}
-		baseObjectMeta.DeepCopyInto(&sts.ObjectMeta)
-		workload = sts
+	var outObj []runtime.Object
+	kind := g.workloadKind(svc)
+	if len(g.versions) == 0 {
+		outObj = append(outObj, g.workload(kind, baseObjectMeta, svc.Replicas, podTemplateSpec)...)
+		outObj = append(outObj, g.services(svc, kind, baseObjectMeta)...)
 	} else {
-		surge := intstr.FromString("25%")
-		deployment := &appsv1.Deployment{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "Deployment",
-				APIVersion: "apps/v1",
-			},
-			Spec: appsv1.DeploymentSpec{
-				Replicas: &repl,
-				Strategy: appsv1.DeploymentStrategy{
-					Type: appsv1.RollingUpdateDeploymentStrategyType,
-					RollingUpdate: &appsv1.RollingUpdateDeployment{
-						MaxSurge:       &surge,
-						MaxUnavailable: &surge,
-					},
-				},
-				Selector: &metav1.LabelSelector{
-					MatchLabels: map[string]string{
-						"app": name,
-					},
-				},
-				Template: podTemplateSpec,
-			},
+		for _, version := range g.versions {
+			versionObjectMeta := versionedObjectMeta(baseObjectMeta, version)
+			versionTemplateSpec := podTemplateSpec.DeepCopy()
+			for k, v := range versionObjectMeta.Labels {
+				versionTemplateSpec.Labels[k] = v
+			}
+			outObj = append(outObj, g.workload(kind, versionObjectMeta, svc.Replicas, *versionTemplateSpec)...)
+			outObj = append(outObj, g.services(svc, kind, versionObjectMeta)...)
 		}
-		baseObjectMeta.DeepCopyInto(&deployment.ObjectMeta)
-		workload = deployment
-	}
-
-	http := "http"
-	service := &v1.Service{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "Service",
-			APIVersion: "v1",
-		},
-		Spec: v1.ServiceSpec{
-			Selector: map[string]string{
-				"app": name,
-			},
-			Ports: []v1.ServicePort{
-				{
-					Name:        "api",
-					AppProtocol: &http,
-					Port:        g.port,
-					TargetPort:  intstr.FromInt32(g.port),
-				},
-			},
-		},
-	}
-	baseObjectMeta.DeepCopyInto(&service.ObjectMeta)
-	outObj := []runtime.Object{
-		workload,
-		service,
+		outObj = append(outObj, g.service(svc, baseObjectMeta), g.trafficSplit(name, g.namespaceOf(svc)))
 	}
 
 	if g.configMapGenerator != nil {
-		conf, err := g.configMapGenerator(g.formatters, svc)
+		conf, err := g.configMapGenerator(formatters, svc)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -326,7 +378,7 @@ func (g generator) Apply(svc apis.Service) ([]runtime.Object, []byte, error) {
 			},
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      name,
-				Namespace: g.namespace,
+				Namespace: g.namespaceOf(svc),
 				Labels: map[string]string{
 					"app": name,
 				},
@@ -341,3 +393,19 @@ func (g generator) Apply(svc apis.Service) ([]runtime.Object, []byte, error) {
 
 	return outObj, nil, nil
 }
+
+// service creates the Service selecting the pods by the selector labels of its metadata.
+func (g generator) service(svc apis.Service, objectMeta metav1.ObjectMeta) *v1.Service {
+	service := &v1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		Spec: v1.ServiceSpec{
+			Selector: selectorLabels(objectMeta.Labels),
+			Ports:    g.servicePorts(svc),
+		},
+	}
+	objectMeta.DeepCopyInto(&service.ObjectMeta)
+	return service
+}
@@ -2,10 +2,18 @@ package k8s_test
 
 import (
 	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/kong/mesh-perf/pkg/graph/apis"
 	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/fakeservice"
 )
 
 func TestSimple(t *testing.T) {
@@ -27,3 +35,464 @@ func TestSimple(t *testing.T) {
 	}
 	println(buf.String())
 }
+
+func TestUnmeshed(t *testing.T) {
+	graph := apis.GenerateRandomMesh(1, 10, 50, 1, 1)
+	var unmeshed []int
+	for _, svc := range graph.Services {
+		if k8s.Unmeshed(svc, 30) {
+			unmeshed = append(unmeshed, svc.Idx)
+		}
+	}
+	if !reflect.DeepEqual([]int{3, 6, 9}, unmeshed) {
+		t.Fatalf("expected unmeshed services: [3 6 9], got: %v", unmeshed)
+	}
+	if _, err := k8s.NewGenerator(k8s.WithUnmeshed(101)); err == nil {
+		t.Fatal("expected an error for more than 100% of unmeshed services")
+	}
+}
+
+func TestResourceProfile(t *testing.T) {
+	sidecar := v1.ResourceRequirements{
+		Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("20m")},
+		Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("128Mi")},
+	}
+	encoder, err := k8s.NewGenerator(
+		k8s.WithNamespace("foo"),
+		k8s.WithImage("nginx"),
+		k8s.WithPort(8080),
+		k8s.WithResourceProfileFn(func(svc apis.Service) k8s.ResourceProfile {
+			profile := k8s.DefaultResourceProfile()
+			if svc.Idx == 1 {
+				profile.Sidecar = sidecar
+			}
+			return profile
+		}),
+	)
+	if err != nil {
+		t.Fatal("failed creating a generator", err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = encoder.Apply(buf, apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	out := buf.String()
+	for s, count := range map[string]int{
+		"kuma.io/sidecar-proxy-cpu-requests: 20m":    1,
+		"kuma.io/sidecar-proxy-memory-limits: 128Mi": 1,
+		"kuma.io/sidecar-proxy-cpu-limits":           0,
+		"kuma.io/sidecar-proxy-memory-requests":      0,
+		"kind: ContainerPatch":                       0,
+	} {
+		if got := strings.Count(out, s); got != count {
+			t.Errorf("expected %d of %q, got: %d\n%s", count, s, got, out)
+		}
+	}
+
+	if cpu := k8s.DefaultResourceProfile().PodCPURequest(); cpu.MilliValue() != 150 {
+		t.Errorf("expected default pod CPU request of 150m, got: %s", cpu.String())
+	}
+}
+
+func TestVersions(t *testing.T) {
+	encoder, err := k8s.NewGenerator(
+		k8s.WithNamespace("foo"),
+		k8s.WithImage("nginx"),
+		k8s.WithPort(8080),
+		k8s.WithVersions(k8s.Version{Name: "v1", Weight: 90}, k8s.Version{Name: "v2", Weight: 10}),
+	)
+	if err != nil {
+		t.Fatal("failed creating a generator", err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = encoder.Apply(buf, apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	out := buf.String()
+	for s, count := range map[string]int{
+		"kind: Deployment":    4,
+		"kind: Service":       6,
+		"kind: MeshHTTPRoute": 2,
+		"version: v2":         10,
+	} {
+		if got := strings.Count(out, s); got != count {
+			t.Errorf("expected %d of %q, got: %d", count, s, got)
+		}
+	}
+	for _, s := range []string{
+		"name: microservice-001-v2",
+		"name: microservice-001-split",
+		"weight: 90",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected output to contain %q, got:\n%s", s, out)
+		}
+	}
+}
+
+func TestExternal(t *testing.T) {
+	graph := apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1, External: true},
+		},
+	}
+	upstreams := func(formatters k8s.Formatters, svc apis.Service, template *v1.PodTemplateSpec) error {
+		for _, edge := range svc.Edges {
+			template.Annotations = map[string]string{"upstream": formatters.Url(edge, 8080)}
+		}
+		return nil
+	}
+	type testCase struct {
+		desc     string
+		opts     []k8s.Option
+		contains []string
+	}
+	tests := []testCase{
+		{
+			desc: "MeshExternalService",
+			contains: []string{
+				"kind: MeshExternalService",
+				"kind: MeshTrafficPermission",
+				"address: microservice-001.mesh-perf-external.svc.cluster.local",
+				"upstream: http://microservice-001.extsvc.mesh.local:8080",
+				"name: mesh-perf-external",
+				"kuma.io/sidecar-injection: disabled",
+			},
+		},
+		{
+			desc: "MeshPassthrough",
+			opts: []k8s.Option{k8s.WithMeshPassthrough(), k8s.WithExternalNamespace("ext")},
+			contains: []string{
+				"kind: MeshPassthrough",
+				"value: microservice-001.ext.svc.cluster.local",
+				"upstream: http://microservice-001.ext.svc.cluster.local:8080",
+			},
+		},
+	}
+	for _, tc := range tests {
+		encoder, err := k8s.NewGenerator(append([]k8s.Option{
+			k8s.WithNamespace("foo"),
+			k8s.WithImage("nginx"),
+			k8s.WithPort(8080),
+			k8s.WithPodTemplateSpecMutators(upstreams),
+		}, tc.opts...)...)
+		if err != nil {
+			t.Fatalf("test: %s, failed creating a generator: %v", tc.desc, err)
+		}
+		buf := bytes.NewBuffer([]byte{})
+		if err := encoder.Apply(buf, graph); err != nil {
+			t.Fatalf("test: %s, failed: %v", tc.desc, err)
+		}
+		out := buf.String()
+		for _, s := range tc.contains {
+			if !strings.Contains(out, s) {
+				t.Errorf("test: %s, expected output to contain %q, got:\n%s", tc.desc, s, out)
+			}
+		}
+	}
+}
+
+func TestWorkloadKinds(t *testing.T) {
+	kinds := []k8s.WorkloadKind{k8s.DaemonSet, k8s.Job, k8s.CronJob, k8s.Pod, k8s.StatefulSet}
+	encoder, err := k8s.NewGenerator(
+		k8s.WithNamespace("foo"),
+		k8s.WithImage("nginx"),
+		k8s.WithPort(8080),
+		k8s.WithJobLifetime(30*time.Second),
+		k8s.WithWorkloadKindFn(func(svc apis.Service) k8s.WorkloadKind {
+			return kinds[svc.Idx]
+		}),
+	)
+	if err != nil {
+		t.Fatal("failed creating a generator", err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = encoder.Apply(buf, apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 2, Edges: []int{1}, Idx: 0},
+			{Replicas: 2, Edges: []int{}, Idx: 1},
+			{Replicas: 2, Edges: []int{}, Idx: 2},
+			{Replicas: 2, Edges: []int{}, Idx: 3},
+			{Replicas: 2, Edges: []int{}, Idx: 4},
+		},
+	})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	out := buf.String()
+	for s, count := range map[string]int{
+		"kind: DaemonSet":            1,
+		"kind: Job":                  1,
+		"kind: CronJob":              1,
+		"kind: Pod":                  2,
+		"kind: StatefulSet":          1,
+		"kind: Service":              5,
+		"activeDeadlineSeconds: 30":  2,
+		"restartPolicy: Never":       2,
+		"name: microservice-003-1\n": 1,
+	} {
+		if got := strings.Count(out, s); got != count {
+			t.Errorf("expected %d of %q, got: %d", count, s, got)
+		}
+	}
+}
+
+func TestPlacement(t *testing.T) {
+	encoder, err := k8s.NewGenerator(
+		k8s.WithNamespace("foo"),
+		k8s.WithImage("nginx"),
+		k8s.WithPort(8080),
+		k8s.WithPlacementFn(func(svc apis.Service) k8s.Placement {
+			placement := k8s.Placement{
+				TopologySpread: []k8s.TopologySpread{
+					{TopologyKey: "topology.kubernetes.io/zone", MaxSkew: 1},
+					{TopologyKey: "kubernetes.io/hostname", MaxSkew: 1, ScheduleAnyway: true, AcrossGraph: true},
+				},
+				NodeSelector:      map[string]string{"pool": "perf"},
+				Tolerations:       []v1.Toleration{{Key: "perf", Operator: v1.TolerationOpExists}},
+				PriorityClassName: "perf-high",
+			}
+			if svc.Idx == 0 {
+				placement.AntiAffinity = &k8s.AntiAffinity{TopologyKey: "kubernetes.io/hostname", Required: true}
+			}
+			return placement
+		}),
+	)
+	if err != nil {
+		t.Fatal("failed creating a generator", err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = encoder.Apply(buf, apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 2, Edges: []int{1}, Idx: 0},
+			{Replicas: 2, Edges: []int{}, Idx: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	out := buf.String()
+	for s, count := range map[string]int{
+		"topologyKey: topology.kubernetes.io/zone":         2,
+		"whenUnsatisfiable: DoNotSchedule":                 2,
+		"requiredDuringSchedulingIgnoredDuringExecution:":  1,
+		"topologyKey: kubernetes.io/hostname":              3,
+		"whenUnsatisfiable: ScheduleAnyway":                2,
+		"mesh-perf/graph: \"true\"":                        4,
+		"pool: perf":                                       2,
+		"key: perf":                                        2,
+		"priorityClassName: perf-high":                     2,
+		"preferredDuringSchedulingIgnoredDuringExecution:": 0,
+	} {
+		if got := strings.Count(out, s); got != count {
+			t.Errorf("expected %d of %q, got: %d", count, s, got)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	encoder, err := k8s.NewGenerator(
+		k8s.WithNamespace("foo"),
+		k8s.WithImage("nginx"),
+		k8s.WithPort(8080),
+		k8s.WithConfigMapGenerator(func(k8s.Formatters, apis.Service) (string, error) {
+			return "", nil
+		}),
+		k8s.WithLabels(map[string]string{"team": "perf", "app": "ignored"}),
+		k8s.WithLabelsFn(func(svc apis.Service) map[string]string {
+			return map[string]string{"kuma.io/zone": fmt.Sprintf("zone-%d", svc.Idx%2)}
+		}),
+		k8s.WithAnnotations(map[string]string{"perf/run": "1"}),
+	)
+	if err != nil {
+		t.Fatal("failed creating a generator", err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = encoder.Apply(buf, apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	out := buf.String()
+	// on the Deployment, its pod template, the Service and the ConfigMap of each service
+	for s, count := range map[string]int{
+		"team: perf":           8,
+		"kuma.io/zone: zone-0": 4,
+		"kuma.io/zone: zone-1": 4,
+		"perf/run: \"1\"":      8,
+		"app: ignored":         0,
+		// selectors only select by the app label
+		"selector:\n    app: microservice-000\n":                     1,
+		"selector:\n    matchLabels:\n      app: microservice-000\n": 1,
+	} {
+		if got := strings.Count(out, s); got != count {
+			t.Errorf("expected %d of %q, got: %d", count, s, got)
+		}
+	}
+}
+
+func TestPorts(t *testing.T) {
+	encoder, err := k8s.NewGenerator(
+		k8s.WithNamespace("foo"),
+		k8s.WithImage("nginx"),
+		k8s.WithPort(8080),
+		k8s.WithExtraPorts(k8s.ServicePort{Name: "admin", Port: 9000, AppProtocol: "grpc"}, k8s.ServicePort{Name: "metrics", Port: 9100}),
+		k8s.AsStatefulSet(),
+		k8s.WithHeadlessServices(),
+	)
+	if err != nil {
+		t.Fatal("failed creating a generator", err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = encoder.Apply(buf, apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	out := buf.String()
+	for s, count := range map[string]int{
+		"kind: Service\n":                        4,
+		"clusterIP: None":                        2,
+		"serviceName: microservice-000-headless": 1,
+		"name: microservice-000-headless":        1,
+		"appProtocol: grpc":                      4,
+		"name: metrics\n    port: 9100":          4,
+	} {
+		if got := strings.Count(out, s); got != count {
+			t.Errorf("expected %d of %q, got: %d", count, s, got)
+		}
+	}
+
+	for _, ports := range [][]k8s.ServicePort{
+		{{Name: "api", Port: 9000}},
+		{{Name: "admin", Port: 9000}, {Name: "admin", Port: 9001}},
+		{{Name: "admin", Port: 0}},
+		{{Port: 9000}},
+	} {
+		if _, err := k8s.NewGenerator(k8s.WithExtraPorts(ports...)); err == nil {
+			t.Errorf("expected ports %v to be invalid", ports)
+		}
+	}
+}
+
+func TestHostnames(t *testing.T) {
+	for got, expected := range map[string]string{
+		k8s.FQDNFormatters("microservice", "foo").Url(1, 80):               "http://microservice-001.foo.svc.cluster.local:80",
+		k8s.MeshServiceFormatters("microservice", "foo", "").Url(1, 80):    "http://microservice-001.foo.svc.mesh.local:80",
+		k8s.MeshMultiZoneServiceFormatters("microservice").Url(1, 80):      "http://microservice-001.mzsvc.mesh.local:80",
+		k8s.MeshServiceFormatters("microservice", "foo", "zone-1").Name(1): "microservice-001",
+		k8s.FQDNFormatters("microservice", "foo").Namespace(1, "bar"):      "bar",
+	} {
+		if got != expected {
+			t.Errorf("expected %q, got: %q", expected, got)
+		}
+	}
+
+	opts := append(
+		fakeservice.GeneratorOpts(fakeservice.WithReachableBackends()),
+		k8s.WithNamespace("foo"),
+		k8s.WithFormatters(k8s.MeshServiceFormatters("fake-service", "foo", "zone-1")),
+		k8s.WithZone("zone-1"),
+		k8s.WithHostnameGenerator(),
+	)
+	encoder, err := k8s.NewGenerator(opts...)
+	if err != nil {
+		t.Fatal("failed creating a generator", err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = encoder.Apply(buf, apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1, 2, 3}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1, Namespace: "bar"},
+			{Replicas: 1, Edges: []int{}, Idx: 2, Zone: "zone-2"},
+			{Replicas: 1, Edges: []int{}, Idx: 3, Zone: "zone-1"},
+		},
+	})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	out := buf.String()
+	for s, count := range map[string]int{
+		"value: http://fake-service-001.bar.svc.mesh.local:9090,http://fake-service-002.foo.svc.zone-2.mesh.local:9090,http://fake-service-003.foo.svc.mesh.local:9090": 1,
+		"kind: HostnameGenerator":                  1,
+		"kind: Namespace":                          2,
+		"kind: Deployment":                         3,
+		"name: fake-service-001\n  namespace: bar": 2,
+		`"namespace":"bar"`:                        1,
+		`"labels":{"k8s.kuma.io/namespace":"foo","kuma.io/display-name":"fake-service-002","kuma.io/zone":"zone-2"}`: 1,
+	} {
+		if got := strings.Count(out, s); got != count {
+			t.Errorf("expected %d of %q, got: %d", count, s, got)
+		}
+	}
+}
+
+func TestLoadGenerator(t *testing.T) {
+	if _, err := k8s.NewGenerator(k8s.WithLoadGenerator(k8s.LoadGenerator{Image: "loadgen"})); err == nil {
+		t.Error("expected a load generator without RPS to fail")
+	}
+
+	opts := append(
+		fakeservice.GeneratorOpts(fakeservice.WithProtocolFn(func(svc apis.Service) fakeservice.Protocol {
+			if svc.Idx == 5 {
+				return fakeservice.GRPC
+			}
+			return fakeservice.HTTP
+		})),
+		k8s.WithNamespace("foo"),
+		k8s.WithZone("zone-1"),
+		k8s.WithLoadGenerator(k8s.LoadGenerator{Image: "registry/mesh-perf-workload:latest", RPS: 0.5}),
+	)
+	encoder, err := k8s.NewGenerator(opts...)
+	if err != nil {
+		t.Fatal("failed creating a generator", err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = encoder.Apply(buf, apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1},
+			{Replicas: 1, Edges: []int{}, Idx: 2, External: true},
+			{Replicas: 1, Edges: []int{}, Idx: 3, Zone: "zone-2"},
+			{Replicas: 1, Edges: []int{1}, Idx: 4},
+			{Replicas: 1, Edges: []int{1}, Idx: 5},
+		},
+	})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	out := buf.String()
+	for s, count := range map[string]int{
+		"name: mesh-perf-load-generator\n  namespace: foo": 1,
+		"- /loadgen": 1,
+		"- name: TARGETS\n          value: http://fake-service-000:9090,http://fake-service-004:9090": 1,
+		"- name: RPS\n          value: \"0.5\"":                                                       1,
+		"prometheus.io/scrape: \"true\"":                                                              1,
+		"traffic.kuma.io/exclude-inbound-ports: \"9090\"":                                             1,
+	} {
+		if got := strings.Count(out, s); got != count {
+			t.Errorf("expected %d of %q, got: %d in:\n%s", count, s, got, out)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package k8s
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+)
+
+// MeshServiceHostnameTemplate is the template of the hostnames of local MeshServices given by the
+// HostnameGenerator of WithHostnameGenerator, <name>.<namespace>.svc.mesh.local.
+const MeshServiceHostnameTemplate = "{{ .DisplayName }}.{{ .Namespace }}.svc.mesh.local"
+
+// FQDNFormatters are formatters calling services on the cluster DNS name of their Service,
+// <name>.<namespace>.svc.cluster.local, so services can call each other across namespaces.
+// Services without a namespace are in the given namespace.
+func FQDNFormatters(baseName string, namespace string) Formatters {
+	return urlFormatters(baseName, func(name string, svc apis.Service) string {
+		return fmt.Sprintf("%s.%s.svc.cluster.local", name, namespaceOr(svc, namespace))
+	})
+}
+
+// MeshServiceFormatters are formatters calling services on the hostnames of their MeshService:
+// <name>.<namespace>.svc.mesh.local for services of the zone, which needs WithHostnameGenerator, and the
+// hostname Kuma gives synced MeshServices, <name>.<namespace>.svc.<zone>.mesh.local, for services of other zones.
+func MeshServiceFormatters(baseName string, namespace string, zone string) Formatters {
+	return urlFormatters(baseName, func(name string, svc apis.Service) string {
+		if svc.Zone != "" && svc.Zone != zone {
+			return fmt.Sprintf("%s.%s.svc.%s.mesh.local", name, namespaceOr(svc, namespace), svc.Zone)
+		}
+		return fmt.Sprintf("%s.%s.svc.mesh.local", name, namespaceOr(svc, namespace))
+	})
+}
+
+// MeshMultiZoneServiceFormatters are formatters calling services on the hostname Kuma gives to
+// MeshMultiZoneServices, <name>.mzsvc.mesh.local, for MeshMultiZoneServices named like the services.
+func MeshMultiZoneServiceFormatters(baseName string) Formatters {
+	return urlFormatters(baseName, func(name string, _ apis.Service) string {
+		return fmt.Sprintf("%s.mzsvc.mesh.local", name)
+	})
+}
+
+func urlFormatters(baseName string, host func(name string, svc apis.Service) string) Formatters {
+	f := SimpleFormatters(baseName)
+	f.ServiceUrl = func(svc apis.Service, port int) string {
+		return fmt.Sprintf("http://%s:%d", host(f.Name(svc.Idx), svc), port)
+	}
+	f.Url = func(idx int, port int) string {
+		return f.ServiceUrl(apis.Service{Idx: idx}, port)
+	}
+	return f
+}
+
+func namespaceOr(svc apis.Service, namespace string) string {
+	if svc.Namespace != "" {
+		return svc.Namespace
+	}
+	return namespace
+}
+
+// WithZone generates only the services of the zone and the ones without a zone, so a graph spread
+// across zones is generated once per zone.
+func WithZone(name string) Option {
+	return OptionFn(func(g *generator) error {
+		g.zone = name
+		return nil
+	})
+}
+
+// WithHostnameGenerator adds a HostnameGenerator giving local MeshServices the hostnames
+// of MeshServiceFormatters.
+func WithHostnameGenerator() Option {
+	return OptionFn(func(g *generator) error {
+		g.hostnameGenerator = true
+		return nil
+	})
+}
+
+func (g generator) inZone(svc apis.Service) bool {
+	return svc.Zone == "" || svc.Zone == g.zone
+}
+
+func (g generator) namespaceOf(svc apis.Service) string {
+	return namespaceOr(svc, g.namespace)
+}
+
+func (g generator) meshServiceHostnameGenerator() *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "kuma.io/v1alpha1",
+			"kind":       "HostnameGenerator",
+			"metadata": map[string]interface{}{
+				"name":      "mesh-perf-local-mesh-service",
+				"namespace": g.systemNamespace,
+			},
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{
+					"meshService": map[string]interface{}{
+						"matchLabels": map[string]interface{}{
+							"kuma.io/origin": "zone",
+						},
+					},
+				},
+				"template": MeshServiceHostnameTemplate,
+			},
+		},
+	}
+}
@@ -0,0 +1,396 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kumahq/kuma/v2/pkg/plugins/runtime/k8s/controllers"
+	"github.com/kumahq/kuma/v2/pkg/plugins/runtime/k8s/metadata"
+	"github.com/kumahq/kuma/v2/pkg/util/pointer"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/files"
+)
+
+const (
+	KustomizationFile = "kustomization.yaml"
+	KustomizeBaseDir  = "base"
+
+	// KumaNamespaceLabel is the label of the namespace of the Kubernetes Service of a MeshService.
+	KumaNamespaceLabel = "k8s.kuma.io/namespace"
+)
+
+// Kustomization is the subset of kustomize's kustomization.yaml we generate.
+type Kustomization struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Namespace  string           `json:"namespace,omitempty"`
+	Resources  []string         `json:"resources"`
+	Images     []KustomizeImage `json:"images,omitempty"`
+	Patches    []KustomizePatch `json:"patches,omitempty"`
+}
+
+type KustomizeImage struct {
+	Name    string `json:"name"`
+	NewName string `json:"newName,omitempty"`
+	NewTag  string `json:"newTag,omitempty"`
+}
+
+type KustomizePatch struct {
+	Patch  string           `json:"patch"`
+	Target *KustomizeTarget `json:"target,omitempty"`
+}
+
+type KustomizeTarget struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// Overlay is a kustomize overlay generated on top of the base, it's written to overlays/<Name>.
+type Overlay struct {
+	Name string
+	// Namespace moves the workloads of the graph and everything in their namespaces to this namespace, the
+	// objects of the system namespace and the stand-ins of external services stay where they are.
+	Namespace string
+	// ImageRegistry replaces the registry of every image used by the workloads, their repository paths are kept.
+	ImageRegistry string
+	// Resources replaces the resources of the app container of every workload.
+	Resources *v1.ResourceRequirements
+}
+
+// ApplyDir writes the graph as a kustomize directory tree with one file per service in dir.
+func (e Generator) ApplyDir(dir string, svc apis.ServiceGraph, overlays ...Overlay) error {
+	out, err := e.Files(svc, overlays...)
+	if err != nil {
+		return err
+	}
+	return out.Write(dir)
+}
+
+// Files generates the graph as a kustomize base (base/) with a file per service and the given overlays (overlays/<name>/).
+func (e Generator) Files(svc apis.ServiceGraph, overlays ...Overlay) (files.Files, error) {
+	out := files.Files{}
+	base := Kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+	}
+	var all []runtime.Object
+	var graphNamespaces []string
+	if e.CommonSetup != nil {
+		objs, raw, err := e.CommonSetup.Generate(svc)
+		if err != nil {
+			return nil, err
+		}
+		b := bytes.NewBuffer(raw)
+		if err := e.encode(b, objs...); err != nil {
+			return nil, err
+		}
+		if b.Len() != 0 {
+			out[path.Join(KustomizeBaseDir, "common.yaml")] = b.Bytes()
+			base.Resources = append(base.Resources, "common.yaml")
+			all = append(all, objs...)
+		}
+	}
+	workloadGenerator := e.workloadGenerator(svc)
+	for _, s := range svc.Services {
+		objs, raw, err := workloadGenerator.Apply(s)
+		if err != nil {
+			return nil, &ServiceGeneratorError{idx: s.Idx, err: err}
+		}
+		// services of other zones have nothing to deploy
+		if len(objs) == 0 && len(raw) == 0 {
+			continue
+		}
+		b := bytes.NewBuffer(raw)
+		if err := e.encode(b, objs...); err != nil {
+			return nil, &ServiceGeneratorError{idx: s.Idx, err: err}
+		}
+		name := path.Join("services", fmt.Sprintf("%s.yaml", serviceFileName(s, objs)))
+		if _, exists := out[path.Join(KustomizeBaseDir, name)]; exists {
+			return nil, &ServiceGeneratorError{idx: s.Idx, err: fmt.Errorf("duplicate file name %q", name)}
+		}
+		out[path.Join(KustomizeBaseDir, name)] = b.Bytes()
+		base.Resources = append(base.Resources, name)
+		all = append(all, objs...)
+		if s.External {
+			continue
+		}
+		for _, obj := range objs {
+			if template, _ := podTemplate(obj); template == nil {
+				continue
+			}
+			if m, err := meta.Accessor(obj); err == nil && !slices.Contains(graphNamespaces, m.GetNamespace()) {
+				graphNamespaces = append(graphNamespaces, m.GetNamespace())
+			}
+		}
+	}
+	b, err := yaml.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+	out[path.Join(KustomizeBaseDir, KustomizationFile)] = b
+
+	for _, overlay := range overlays {
+		if overlay.Name == "" {
+			return nil, errors.New("overlay must have a name")
+		}
+		k, err := overlay.kustomization(all, graphNamespaces)
+		if err != nil {
+			return nil, err
+		}
+		b, err := yaml.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		out[path.Join("overlays", overlay.Name, KustomizationFile)] = b
+	}
+	return out, nil
+}
+
+func (o Overlay) kustomization(objs []runtime.Object, graphNamespaces []string) (Kustomization, error) {
+	k := Kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  []string{path.Join("..", "..", KustomizeBaseDir)},
+	}
+	var targets []KustomizeTarget
+	specPaths := map[KustomizeTarget]string{}
+	var namespacePatches []KustomizePatch
+	for _, obj := range objs {
+		template, templatePath := podTemplate(obj)
+		if template == nil {
+			continue
+		}
+		specPath := templatePath + "/spec"
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		target := KustomizeTarget{Group: gvk.Group, Kind: gvk.Kind}
+		if _, ok := specPaths[target]; !ok {
+			targets = append(targets, target)
+			specPaths[target] = specPath
+		}
+		if o.Namespace != "" {
+			patches, err := o.reachablePatches(obj, template, templatePath, graphNamespaces)
+			if err != nil {
+				return k, err
+			}
+			namespacePatches = append(namespacePatches, patches...)
+		}
+		if o.ImageRegistry == "" {
+			continue
+		}
+		for _, c := range template.Spec.Containers {
+			name := imageName(c.Image)
+			if slices.ContainsFunc(k.Images, func(i KustomizeImage) bool { return i.Name == name }) {
+				continue
+			}
+			k.Images = append(k.Images, KustomizeImage{
+				Name:    name,
+				NewName: fmt.Sprintf("%s/%s", o.ImageRegistry, repositoryPath(name)),
+			})
+		}
+	}
+	if o.Resources != nil {
+		for _, target := range targets {
+			patch, err := yaml.Marshal([]map[string]interface{}{
+				{
+					"op":    "replace",
+					"path":  path.Join(specPaths[target], "containers/0/resources"),
+					"value": o.Resources,
+				},
+			})
+			if err != nil {
+				return k, err
+			}
+			k.Patches = append(k.Patches, KustomizePatch{
+				Patch:  string(patch),
+				Target: &target,
+			})
+		}
+	}
+	if o.Namespace != "" {
+		// the annotations are patched first, their targets are in the namespaces of the base
+		k.Patches = append(k.Patches, namespacePatches...)
+		patches, err := o.namespacePatches(objs, graphNamespaces)
+		if err != nil {
+			return k, err
+		}
+		k.Patches = append(k.Patches, patches...)
+	}
+	return k, nil
+}
+
+// namespacePatches move the objects of the namespaces of the graph to the namespace of the overlay. Unlike the
+// namespace of a kustomization, they leave alone the objects of the system namespace and the stand-ins of
+// external services. The first Namespace of the graph is renamed to the namespace of the overlay, the others
+// are deleted.
+func (o Overlay) namespacePatches(objs []runtime.Object, graphNamespaces []string) ([]KustomizePatch, error) {
+	var patches []KustomizePatch
+	renamed := slices.Contains(graphNamespaces, o.Namespace)
+	for _, obj := range objs {
+		ns, ok := obj.(*v1.Namespace)
+		if !ok || !slices.Contains(graphNamespaces, ns.Name) || ns.Name == o.Namespace {
+			continue
+		}
+		target := &KustomizeTarget{Kind: "Namespace", Name: ns.Name}
+		if !renamed {
+			renamed = true
+			patch, err := yaml.Marshal([]map[string]interface{}{
+				{"op": "replace", "path": "/metadata/name", "value": o.Namespace},
+			})
+			if err != nil {
+				return nil, err
+			}
+			patches = append(patches, KustomizePatch{Patch: string(patch), Target: target})
+			continue
+		}
+		patch, err := yaml.Marshal(map[string]interface{}{
+			"$patch":     "delete",
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]interface{}{"name": ns.Name},
+		})
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, KustomizePatch{Patch: string(patch), Target: target})
+	}
+	for _, namespace := range graphNamespaces {
+		if namespace == o.Namespace {
+			continue
+		}
+		patch, err := yaml.Marshal([]map[string]interface{}{
+			{"op": "replace", "path": "/metadata/namespace", "value": o.Namespace},
+		})
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, KustomizePatch{
+			Patch:  string(patch),
+			Target: &KustomizeTarget{Namespace: namespace},
+		})
+	}
+	return patches, nil
+}
+
+// reachablePatches regenerate the reachable backends and services of the pods of a workload with the
+// namespace of the overlay, in place of the namespaces of the graph.
+func (o Overlay) reachablePatches(
+	obj runtime.Object,
+	template *v1.PodTemplateSpec,
+	templatePath string,
+	graphNamespaces []string,
+) ([]KustomizePatch, error) {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	target := &KustomizeTarget{Group: gvk.Group, Kind: gvk.Kind, Name: m.GetName(), Namespace: m.GetNamespace()}
+	var ops []map[string]interface{}
+	if annotation, ok := template.Annotations[metadata.KumaReachableBackends]; ok {
+		refs := controllers.ReachableBackendRefs{}
+		if err := json.Unmarshal([]byte(annotation), &refs); err != nil {
+			return nil, fmt.Errorf("annotation %s of %s: %w", metadata.KumaReachableBackends, m.GetName(), err)
+		}
+		for _, ref := range refs.Refs {
+			if ref.Namespace != nil && slices.Contains(graphNamespaces, *ref.Namespace) {
+				ref.Namespace = pointer.To(o.Namespace)
+			}
+			if namespace, ok := ref.Labels[KumaNamespaceLabel]; ok && slices.Contains(graphNamespaces, namespace) {
+				ref.Labels[KumaNamespaceLabel] = o.Namespace
+			}
+		}
+		value, err := json.Marshal(refs)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, annotationOp(templatePath, metadata.KumaReachableBackends, string(value)))
+	}
+	if annotation, ok := template.Annotations[metadata.KumaTransparentProxyingReachableServicesAnnotation]; ok {
+		// the names of the services are <name>_<namespace>_svc_<port>
+		for _, namespace := range graphNamespaces {
+			annotation = strings.ReplaceAll(annotation, "_"+namespace+"_svc_", "_"+o.Namespace+"_svc_")
+		}
+		ops = append(ops, annotationOp(templatePath, metadata.KumaTransparentProxyingReachableServicesAnnotation, annotation))
+	}
+	if len(ops) == 0 {
+		return nil, nil
+	}
+	patch, err := yaml.Marshal(ops)
+	if err != nil {
+		return nil, err
+	}
+	return []KustomizePatch{{Patch: string(patch), Target: target}}, nil
+}
+
+func annotationOp(templatePath string, annotation string, value string) map[string]interface{} {
+	// "/" is escaped as "~1" in JSON pointers
+	key := strings.ReplaceAll(strings.ReplaceAll(annotation, "~", "~0"), "/", "~1")
+	return map[string]interface{}{
+		"op":    "replace",
+		"path":  templatePath + "/metadata/annotations/" + key,
+		"value": value,
+	}
+}
+
+// imageName is the image without its tag or digest, the port of a registry isn't a tag.
+func imageName(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	return name
+}
+
+// repositoryPath is the path of an image name in its registry, without the host of the registry. Like the
+// container runtimes, the first component is a host when it has a dot or a port, or is localhost.
+func repositoryPath(name string) string {
+	host, rest, ok := strings.Cut(name, "/")
+	if ok && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		return rest
+	}
+	return name
+}
+
+// serviceFileName names the file of a service after its first object, the workload.
+func serviceFileName(svc apis.Service, objs []runtime.Object) string {
+	if len(objs) > 0 {
+		if m, err := meta.Accessor(objs[0]); err == nil && m.GetName() != "" {
+			return m.GetName()
+		}
+	}
+	return fmt.Sprintf("service-%03d", svc.Idx)
+}
+
+// podTemplate returns the pod template of a workload and the JSON pointer to it, a bare Pod is its own template.
+func podTemplate(obj runtime.Object) (*v1.PodTemplateSpec, string) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return &o.Spec.Template, "/spec/template"
+	case *appsv1.StatefulSet:
+		return &o.Spec.Template, "/spec/template"
+	case *appsv1.DaemonSet:
+		return &o.Spec.Template, "/spec/template"
+	case *batchv1.Job:
+		return &o.Spec.Template, "/spec/template"
+	case *batchv1.CronJob:
+		return &o.Spec.JobTemplate.Spec.Template, "/spec/jobTemplate/spec/template"
+	case *v1.Pod:
+		return &v1.PodTemplateSpec{ObjectMeta: o.ObjectMeta, Spec: o.Spec}, ""
+	}
+	return nil, ""
+}
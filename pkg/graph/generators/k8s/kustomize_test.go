@@ -0,0 +1,147 @@
+package k8s_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/fakeservice"
+)
+
+func TestKustomize(t *testing.T) {
+	generator, err := k8s.NewGenerator(
+		k8s.WithNamespace("foo"),
+		k8s.WithImage("localhost:5000/library/nginx:1.27"),
+		k8s.WithPort(8080),
+		// the same base name in another repository
+		k8s.WithPodTemplateSpecMutators(func(_ k8s.Formatters, svc apis.Service, template *v1.PodTemplateSpec) error {
+			if svc.Idx == 1 {
+				template.Spec.Containers[0].Image = "docker.io/bitnami/nginx:1.27"
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal("failed creating a simple generator", err)
+	}
+	out, err := generator.Files(apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 2, Edges: []int{1}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1},
+		},
+	}, k8s.Overlay{
+		Name:          "perf",
+		Namespace:     "bar",
+		ImageRegistry: "registry.local",
+		Resources: &v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("50m")},
+		},
+	})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+
+	expectedNames := []string{
+		"base/common.yaml",
+		"base/kustomization.yaml",
+		"base/services/microservice-000.yaml",
+		"base/services/microservice-001.yaml",
+		"overlays/perf/kustomization.yaml",
+	}
+	if !reflect.DeepEqual(expectedNames, out.Names()) {
+		t.Fatalf("expected files: %v, got: %v", expectedNames, out.Names())
+	}
+
+	base := k8s.Kustomization{}
+	if err := yaml.Unmarshal(out["base/kustomization.yaml"], &base); err != nil {
+		t.Fatal("failed parsing base kustomization", err)
+	}
+	expectedResources := []string{"common.yaml", "services/microservice-000.yaml", "services/microservice-001.yaml"}
+	if !reflect.DeepEqual(expectedResources, base.Resources) {
+		t.Fatalf("expected resources: %v, got: %v", expectedResources, base.Resources)
+	}
+
+	overlay := k8s.Kustomization{}
+	if err := yaml.Unmarshal(out["overlays/perf/kustomization.yaml"], &overlay); err != nil {
+		t.Fatal("failed parsing overlay kustomization", err)
+	}
+	if overlay.Namespace != "" {
+		t.Errorf("expected the namespace to be moved by patches, got: %s", overlay.Namespace)
+	}
+	expectedImages := []k8s.KustomizeImage{
+		{Name: "localhost:5000/library/nginx", NewName: "registry.local/library/nginx"},
+		{Name: "docker.io/bitnami/nginx", NewName: "registry.local/bitnami/nginx"},
+	}
+	if !reflect.DeepEqual(expectedImages, overlay.Images) {
+		t.Errorf("expected images: %v, got: %v", expectedImages, overlay.Images)
+	}
+	expectedTargets := []k8s.KustomizeTarget{
+		{Group: "apps", Kind: "Deployment"},
+		{Kind: "Namespace", Name: "foo"},
+		{Namespace: "foo"},
+	}
+	var targets []k8s.KustomizeTarget
+	for _, patch := range overlay.Patches {
+		targets = append(targets, *patch.Target)
+	}
+	if !reflect.DeepEqual(expectedTargets, targets) {
+		t.Errorf("expected patch targets: %v, got: %v", expectedTargets, targets)
+	}
+}
+
+func TestKustomizeNamespace(t *testing.T) {
+	opts := append(
+		fakeservice.GeneratorOpts(fakeservice.WithReachableBackends()),
+		k8s.WithNamespace("foo"),
+		k8s.WithImage("nginx@sha256:0123456789abcdef"),
+	)
+	generator, err := k8s.NewGenerator(opts...)
+	if err != nil {
+		t.Fatal("failed creating a generator", err)
+	}
+	out, err := generator.Files(apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1, 2}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1, Namespace: "baz"},
+			{Replicas: 1, Edges: []int{}, Idx: 2, External: true},
+		},
+	}, k8s.Overlay{Name: "perf", Namespace: "bar", ImageRegistry: "registry.local"})
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+
+	overlay := k8s.Kustomization{}
+	if err := yaml.Unmarshal(out["overlays/perf/kustomization.yaml"], &overlay); err != nil {
+		t.Fatal("failed parsing overlay kustomization", err)
+	}
+	expectedImages := []k8s.KustomizeImage{{Name: "nginx", NewName: "registry.local/nginx"}}
+	if !reflect.DeepEqual(expectedImages, overlay.Images) {
+		t.Errorf("expected images: %v, got: %v", expectedImages, overlay.Images)
+	}
+	b, err := yaml.Marshal(overlay.Patches)
+	if err != nil {
+		t.Fatal("failed marshaling patches", err)
+	}
+	patches := string(b)
+	for s, count := range map[string]int{
+		"path: /spec/template/metadata/annotations/kuma.io~1reachable-backends": 2,
+		`"name":"fake-service-001","namespace":"bar"`:                           1,
+		`"namespace":"kong-mesh-system"`:                                        1,
+		"value: bar":                                                            3,
+		"$patch: delete":                                                        1,
+		"namespace: foo":                                                        2,
+		"namespace: baz":                                                        2,
+		k8s.DefaultExternalNamespace:                                            0,
+		k8s.DefaultSystemNamespace + "\n":                                       0,
+	} {
+		if got := strings.Count(patches, s); got != count {
+			t.Errorf("expected %d of %q, got: %d\n%s", count, s, got, patches)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package k8s
+
+import (
+	"maps"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+)
+
+// WithLabels sets labels on the workloads, pods, Services and ConfigMaps of every service.
+func WithLabels(labels map[string]string) Option {
+	return WithLabelsFn(func(apis.Service) map[string]string {
+		return labels
+	})
+}
+
+// WithLabelsFn sets labels on the workloads, pods, Services and ConfigMaps of each service, they're added to
+// the labels of the previous options. The app label can't be overridden, workloads and Services select pods by it.
+func WithLabelsFn(fn func(svc apis.Service) map[string]string) Option {
+	return OptionFn(func(g *generator) error {
+		g.labels = append(g.labels, fn)
+		return nil
+	})
+}
+
+// WithAnnotations sets annotations on the workloads, pods, Services and ConfigMaps of every service.
+func WithAnnotations(annotations map[string]string) Option {
+	return WithAnnotationsFn(func(apis.Service) map[string]string {
+		return annotations
+	})
+}
+
+// WithAnnotationsFn sets annotations on the workloads, pods, Services and ConfigMaps of each service, they're
+// added to the annotations of the previous options.
+func WithAnnotationsFn(fn func(svc apis.Service) map[string]string) Option {
+	return OptionFn(func(g *generator) error {
+		g.annotations = append(g.annotations, fn)
+		return nil
+	})
+}
+
+func merged(svc apis.Service, fns []func(svc apis.Service) map[string]string) map[string]string {
+	out := map[string]string{}
+	for _, fn := range fns {
+		maps.Copy(out, fn(svc))
+	}
+	return out
+}
+
+// selectorLabels are the labels workloads and Services select their pods by.
+func selectorLabels(labels map[string]string) map[string]string {
+	out := map[string]string{}
+	for _, key := range []string{"app", VersionLabel} {
+		if value, ok := labels[key]; ok {
+			out[key] = value
+		}
+	}
+	return out
+}
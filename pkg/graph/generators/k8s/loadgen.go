@@ -0,0 +1,131 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/loadgen"
+)
+
+const (
+	// LoadGeneratorName is the name of the Deployment of the load generator.
+	LoadGeneratorName = "mesh-perf-load-generator"
+	// LoadGeneratorMetricsPort is the port the load generator serves its metrics on, outside the mesh.
+	LoadGeneratorMetricsPort = 9090
+
+	KumaExcludeInboundPortsAnnotation = "traffic.kuma.io/exclude-inbound-ports"
+)
+
+// LoadGenerator is the load generator of WithLoadGenerator.
+type LoadGenerator struct {
+	// Image is an image with the load generator of tools/loadgen at /loadgen, like the workload of workloadapp.
+	Image string
+	// RPS is the rate of requests sent to each root.
+	RPS float64
+	// Resources are the resources of the load generator, 100m of CPU and 64Mi of memory when they're not set.
+	Resources v1.ResourceRequirements
+}
+
+// WithLoadGenerator deploys a load generator in the mesh calling every root of the graph over HTTP, so the
+// impact of changes of the mesh on traffic can be measured. It only speaks HTTP, roots with the grpc
+// appProtocol aren't called. Prometheus scrapes the latency and status codes of
+// its requests from its pod.
+func WithLoadGenerator(loadGenerator LoadGenerator) Option {
+	return OptionFn(func(g *generator) error {
+		if loadGenerator.Image == "" {
+			return errors.New("the load generator must have an image")
+		}
+		if loadGenerator.RPS <= 0 {
+			return fmt.Errorf("invalid load generator RPS %v", loadGenerator.RPS)
+		}
+		g.loadGenerator = &loadGenerator
+		return nil
+	})
+}
+
+// loadGeneratorDeployment generates the load generator of the HTTP roots of the graph in the zone, nothing when
+// there are none.
+func (g generator) loadGeneratorDeployment(svcs apis.ServiceGraph) []runtime.Object {
+	g.graph = &svcs
+	formatters := g.formattersForGraph()
+	var targets []string
+	for _, idx := range svcs.Roots() {
+		svc := svcs.Services[idx]
+		if svc.External || !g.inZone(svc) || (g.appProtocol != nil && g.appProtocol(svc) == "grpc") {
+			continue
+		}
+		targets = append(targets, formatters.Url(idx, int(g.port)))
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	resources := g.loadGenerator.Resources
+	if len(resources.Requests) == 0 && len(resources.Limits) == 0 {
+		resources = v1.ResourceRequirements{
+			Limits: v1.ResourceList{
+				v1.ResourceMemory: resource.MustParse("64Mi"),
+			},
+			Requests: v1.ResourceList{
+				v1.ResourceMemory: resource.MustParse("64Mi"),
+				v1.ResourceCPU:    resource.MustParse("100m"),
+			},
+		}
+	}
+	port := strconv.Itoa(LoadGeneratorMetricsPort)
+	objectMeta := metav1.ObjectMeta{
+		Name:      LoadGeneratorName,
+		Namespace: g.namespace,
+		Labels: map[string]string{
+			"app": LoadGeneratorName,
+		},
+	}
+	podTemplateSpec := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				"app": LoadGeneratorName,
+			},
+			Annotations: map[string]string{
+				"prometheus.io/scrape": "true",
+				"prometheus.io/port":   port,
+				"prometheus.io/path":   "/metrics",
+				// the metrics are scraped around the sidecar, the load generator has no Service
+				KumaExcludeInboundPortsAnnotation: port,
+			},
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name:            "app",
+					Image:           g.loadGenerator.Image,
+					ImagePullPolicy: v1.PullAlways,
+					Command:         []string{"/loadgen"},
+					Env: []v1.EnvVar{
+						{Name: loadgen.TargetsEnv, Value: strings.Join(targets, ",")},
+						{Name: loadgen.RPSEnv, Value: strconv.FormatFloat(g.loadGenerator.RPS, 'f', -1, 64)},
+						{Name: loadgen.ListenAddrEnv, Value: fmt.Sprintf("0.0.0.0:%d", LoadGeneratorMetricsPort)},
+					},
+					ReadinessProbe: &v1.Probe{
+						ProbeHandler: v1.ProbeHandler{
+							HTTPGet: &v1.HTTPGetAction{
+								Port: intstr.FromInt32(LoadGeneratorMetricsPort),
+								Path: "/health",
+							},
+						},
+					},
+					Resources: resources,
+				},
+			},
+		},
+	}
+	return g.workload(Deployment, objectMeta, 1, podTemplateSpec)
+}
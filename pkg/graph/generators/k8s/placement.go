@@ -0,0 +1,108 @@
+package k8s
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+)
+
+// Placement controls the nodes the pods of a service are scheduled on, so repeated runs on the same node pool
+// place pods the same way.
+type Placement struct {
+	// TopologySpread spreads the pods of the service evenly across the domains of each topology key.
+	TopologySpread []TopologySpread
+	// AntiAffinity keeps the pods of the service apart across the domains of a topology key.
+	AntiAffinity *AntiAffinity
+	NodeSelector map[string]string
+	Tolerations  []v1.Toleration
+	// PriorityClassName is the PriorityClass of the pods, it must exist in the cluster.
+	PriorityClassName string
+}
+
+// GraphPodLabel is the label of the pods spread across the whole graph, see TopologySpread.AcrossGraph.
+const GraphPodLabel = "mesh-perf/graph"
+
+type TopologySpread struct {
+	// TopologyKey is the node label of the domains, e.g. kubernetes.io/hostname or topology.kubernetes.io/zone.
+	TopologyKey string
+	MaxSkew     int32
+	// ScheduleAnyway schedules pods that would break the skew instead of leaving them pending.
+	ScheduleAnyway bool
+	// AcrossGraph counts the pods of every service with the same spread, labeled with GraphPodLabel, instead of
+	// the pods of the service only. The skew of a service with fewer replicas than domains is always satisfied,
+	// so only a spread across the graph places its pods evenly. Pods are counted within their namespace.
+	AcrossGraph bool
+}
+
+type AntiAffinity struct {
+	TopologyKey string
+	// Required leaves pods pending when every domain already runs a pod of the service,
+	// otherwise the scheduler only prefers the domains without one.
+	Required bool
+}
+
+// WithPlacement sets the same placement for every service.
+func WithPlacement(placement Placement) Option {
+	return WithPlacementFn(func(apis.Service) Placement {
+		return placement
+	})
+}
+
+// WithPlacementFn sets the placement of each service.
+func WithPlacementFn(fn func(svc apis.Service) Placement) Option {
+	return OptionFn(func(g *generator) error {
+		g.placement = fn
+		return nil
+	})
+}
+
+// apply sets the placement on a pod template, its pods are selected by their app label, or by the GraphPodLabel
+// for spreads across the graph.
+func (p Placement) apply(template *v1.PodTemplateSpec) {
+	selector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"app": template.Labels["app"],
+		},
+	}
+	graphSelector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			GraphPodLabel: "true",
+		},
+	}
+	for _, spread := range p.TopologySpread {
+		whenUnsatisfiable := v1.DoNotSchedule
+		if spread.ScheduleAnyway {
+			whenUnsatisfiable = v1.ScheduleAnyway
+		}
+		spreadSelector := selector
+		if spread.AcrossGraph {
+			spreadSelector = graphSelector
+			template.Labels[GraphPodLabel] = "true"
+		}
+		template.Spec.TopologySpreadConstraints = append(template.Spec.TopologySpreadConstraints, v1.TopologySpreadConstraint{
+			MaxSkew:           max(spread.MaxSkew, 1),
+			TopologyKey:       spread.TopologyKey,
+			WhenUnsatisfiable: whenUnsatisfiable,
+			LabelSelector:     spreadSelector,
+		})
+	}
+	if p.AntiAffinity != nil {
+		term := v1.PodAffinityTerm{
+			LabelSelector: selector,
+			TopologyKey:   p.AntiAffinity.TopologyKey,
+		}
+		antiAffinity := &v1.PodAntiAffinity{}
+		if p.AntiAffinity.Required {
+			antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = []v1.PodAffinityTerm{term}
+		} else {
+			antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = []v1.WeightedPodAffinityTerm{
+				{Weight: 100, PodAffinityTerm: term},
+			}
+		}
+		template.Spec.Affinity = &v1.Affinity{PodAntiAffinity: antiAffinity}
+	}
+	template.Spec.NodeSelector = p.NodeSelector
+	template.Spec.Tolerations = p.Tolerations
+	template.Spec.PriorityClassName = p.PriorityClassName
+}
@@ -0,0 +1,119 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+)
+
+// APIPortName is the name of the port set by WithPort, services call each other and are probed on it.
+const APIPortName = "api"
+
+// ServicePort is an extra port of the Services, each one is an extra inbound of the pods and an extra
+// port of the MeshService.
+type ServicePort struct {
+	Name        string
+	Port        int32
+	AppProtocol string
+}
+
+// WithExtraPorts adds ports to the Service of every service, next to the api port.
+func WithExtraPorts(ports ...ServicePort) Option {
+	return OptionFn(func(g *generator) error {
+		names := map[string]struct{}{APIPortName: {}}
+		for _, p := range ports {
+			if p.Name == "" {
+				return errors.New("a port must have a name")
+			}
+			if _, ok := names[p.Name]; ok {
+				return fmt.Errorf("duplicate port name %s", p.Name)
+			}
+			if p.Port <= 0 || p.Port > 65535 {
+				return fmt.Errorf("invalid port %d of %s", p.Port, p.Name)
+			}
+			names[p.Name] = struct{}{}
+		}
+		g.extraPorts = ports
+		return nil
+	})
+}
+
+// WithAppProtocolFn sets the appProtocol of the api port of the Service of each service, it's http by default.
+func WithAppProtocolFn(fn func(svc apis.Service) string) Option {
+	return OptionFn(func(g *generator) error {
+		g.appProtocol = fn
+		return nil
+	})
+}
+
+// WithHeadlessServices gives every StatefulSet a headless Service named <name>-headless as its governing service.
+func WithHeadlessServices() Option {
+	return OptionFn(func(g *generator) error {
+		g.headlessServices = true
+		return nil
+	})
+}
+
+// HeadlessName is the name of the headless Service of a StatefulSet.
+func HeadlessName(name string) string {
+	return fmt.Sprintf("%s-headless", name)
+}
+
+func (g generator) servicePorts(svc apis.Service) []v1.ServicePort {
+	appProtocol := "http"
+	if g.appProtocol != nil {
+		appProtocol = g.appProtocol(svc)
+	}
+	out := []v1.ServicePort{
+		{
+			Name:        APIPortName,
+			AppProtocol: &appProtocol,
+			Port:        g.port,
+			TargetPort:  intstr.FromInt32(g.port),
+		},
+	}
+	for _, p := range g.extraPorts {
+		port := v1.ServicePort{
+			Name:       p.Name,
+			Port:       p.Port,
+			TargetPort: intstr.FromInt32(p.Port),
+		}
+		if p.AppProtocol != "" {
+			appProtocol := p.AppProtocol
+			port.AppProtocol = &appProtocol
+		}
+		out = append(out, port)
+	}
+	return out
+}
+
+// headlessService creates the headless Service governing the StatefulSet of the metadata.
+func (g generator) headlessService(svc apis.Service, objectMeta metav1.ObjectMeta) *v1.Service {
+	service := g.service(svc, objectMeta)
+	service.Name = HeadlessName(objectMeta.Name)
+	service.Spec.ClusterIP = v1.ClusterIPNone
+	return service
+}
+
+// services creates the Services of the pods of the metadata.
+func (g generator) services(svc apis.Service, kind WorkloadKind, objectMeta metav1.ObjectMeta) []runtime.Object {
+	out := []runtime.Object{g.service(svc, objectMeta)}
+	if kind == StatefulSet && g.headlessServices {
+		out = append(out, g.headlessService(svc, objectMeta))
+	}
+	return out
+}
+
+// statefulSetServiceName is the name of the governing service of the StatefulSet of the metadata.
+func (g generator) statefulSetServiceName(objectMeta metav1.ObjectMeta) string {
+	if g.headlessServices {
+		return HeadlessName(objectMeta.Name)
+	}
+	return objectMeta.Name
+}
@@ -0,0 +1,93 @@
+package k8s
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+)
+
+const (
+	DefaultSystemNamespace = "kong-mesh-system"
+
+	KumaSidecarCPURequestsAnnotation    = "kuma.io/sidecar-proxy-cpu-requests"
+	KumaSidecarCPULimitsAnnotation      = "kuma.io/sidecar-proxy-cpu-limits"
+	KumaSidecarMemoryRequestsAnnotation = "kuma.io/sidecar-proxy-memory-requests"
+	KumaSidecarMemoryLimitsAnnotation   = "kuma.io/sidecar-proxy-memory-limits"
+)
+
+// DefaultSidecarCPURequest is the CPU request the control plane gives to kuma-dp when a profile doesn't set it.
+var DefaultSidecarCPURequest = resource.MustParse("50m")
+
+// ResourceProfile are the resources of the containers of a service's pods.
+type ResourceProfile struct {
+	// App are the resources of the application container.
+	App v1.ResourceRequirements
+	// Sidecar are the resources of kuma-dp, the CPU and memory set are applied by the annotations of the pod,
+	// the others keep the control plane defaults.
+	Sidecar v1.ResourceRequirements
+}
+
+func DefaultResourceProfile() ResourceProfile {
+	return ResourceProfile{
+		App: v1.ResourceRequirements{
+			Limits: v1.ResourceList{
+				v1.ResourceMemory: resource.MustParse("32Mi"),
+			},
+			Requests: v1.ResourceList{
+				v1.ResourceMemory: resource.MustParse("32Mi"),
+				v1.ResourceCPU:    resource.MustParse("100m"),
+			},
+		},
+	}
+}
+
+// PodCPURequest is the CPU requested by a pod of the profile, the app container and kuma-dp together.
+func (p ResourceProfile) PodCPURequest() resource.Quantity {
+	cpu := p.App.Requests.Cpu().DeepCopy()
+	if sidecar, ok := p.Sidecar.Requests[v1.ResourceCPU]; ok {
+		cpu.Add(sidecar)
+	} else {
+		cpu.Add(DefaultSidecarCPURequest)
+	}
+	return cpu
+}
+
+// sidecarAnnotations are the annotations setting the resources of kuma-dp in the pods of the profile.
+func (p ResourceProfile) sidecarAnnotations() map[string]string {
+	annotations := map[string]string{}
+	for annotation, quantity := range map[string]*resource.Quantity{
+		KumaSidecarCPURequestsAnnotation:    p.Sidecar.Requests.Cpu(),
+		KumaSidecarCPULimitsAnnotation:      p.Sidecar.Limits.Cpu(),
+		KumaSidecarMemoryRequestsAnnotation: p.Sidecar.Requests.Memory(),
+		KumaSidecarMemoryLimitsAnnotation:   p.Sidecar.Limits.Memory(),
+	} {
+		if !quantity.IsZero() {
+			annotations[annotation] = quantity.String()
+		}
+	}
+	return annotations
+}
+
+// WithResourceProfile sets the same resources for every service.
+func WithResourceProfile(profile ResourceProfile) Option {
+	return WithResourceProfileFn(func(apis.Service) ResourceProfile {
+		return profile
+	})
+}
+
+// WithResourceProfileFn sets the resources of each service.
+func WithResourceProfileFn(fn func(svc apis.Service) ResourceProfile) Option {
+	return OptionFn(func(g *generator) error {
+		g.resourceProfile = fn
+		return nil
+	})
+}
+
+// WithSystemNamespace sets the namespace of the control plane, where mesh scoped resources are created.
+func WithSystemNamespace(name string) Option {
+	return OptionFn(func(g *generator) error {
+		g.systemNamespace = name
+		return nil
+	})
+}
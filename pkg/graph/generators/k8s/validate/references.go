@@ -0,0 +1,348 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kumahq/kuma/v2/api/common/v1alpha1"
+	"github.com/kumahq/kuma/v2/pkg/plugins/runtime/k8s/controllers"
+	"github.com/kumahq/kuma/v2/pkg/plugins/runtime/k8s/metadata"
+)
+
+// TotalAnnotationSizeLimit is the limit of the size of the annotations of an object, the API server
+// rejects objects over it.
+const TotalAnnotationSizeLimit = 256 * (1 << 10)
+
+// UpstreamURIsEnv is the variable fake services read their upstreams from.
+const UpstreamURIsEnv = "UPSTREAM_URIS"
+
+// configFileKey is the key of the config file of the ConfigMap of a service, a YAML map of its variables.
+const configFileKey = "config.yaml"
+
+// meshExternalServiceDomain is the domain of the default HostnameGenerator of MeshExternalServices.
+const meshExternalServiceDomain = ".extsvc.mesh.local"
+
+// zonedMeshServiceHost matches the hostnames of MeshServices synced from other zones, <name>.<namespace>.svc.<zone>.mesh.local.
+var zonedMeshServiceHost = regexp.MustCompile(`^[^.]+\.[^.]+\.svc\.[^.]+\.mesh\.local$`)
+
+type workload struct {
+	kind      string
+	namespace string
+	name      string
+	template  v1.PodTemplateSpec
+	selector  *metav1.LabelSelector
+}
+
+type bundle struct {
+	services             map[string]*v1.Service
+	configMaps           map[string]map[string]string
+	meshExternalServices map[string]int64
+	workloads            []workload
+	annotated            []metav1.Object
+}
+
+// References checks the objects of a manifest generated for a graph reference each other consistently:
+// upstream URIs, reachable backends and reachable services resolve to a Service and its port, selectors
+// select pods, ConfigMap volumes have their ConfigMap and annotations fit in the limit of the API server.
+// The errors are grouped by service, the app label of the objects.
+func References(manifest []byte) error {
+	b := bundle{
+		services:             map[string]*v1.Service{},
+		configMaps:           map[string]map[string]string{},
+		meshExternalServices: map[string]int64{},
+	}
+	for _, doc := range documents(manifest) {
+		if err := b.add(doc); err != nil {
+			return err
+		}
+	}
+
+	errs := map[string][]error{}
+	var apps []string
+	report := func(app string, err error) {
+		if _, ok := errs[app]; !ok {
+			apps = append(apps, app)
+		}
+		errs[app] = append(errs[app], err)
+	}
+	for _, w := range b.workloads {
+		app := appOf(&w.template.ObjectMeta, w.name)
+		for _, err := range b.checkWorkload(w) {
+			report(app, fmt.Errorf("%s %s/%s: %w", w.kind, w.namespace, w.name, err))
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(b.services)) {
+		svc := b.services[k]
+		if err := b.checkSelects(svc); err != nil {
+			report(appOf(svc, svc.Name), fmt.Errorf("Service %s/%s: %w", svc.Namespace, svc.Name, err))
+		}
+	}
+	for _, obj := range b.annotated {
+		if size := annotationsSize(obj.GetAnnotations()); size > TotalAnnotationSizeLimit {
+			report(appOf(obj, obj.GetName()), fmt.Errorf("%s/%s: annotations are %d bytes, over the limit of %d",
+				obj.GetNamespace(), obj.GetName(), size, TotalAnnotationSizeLimit))
+		}
+	}
+
+	slices.Sort(apps)
+	var out []error
+	for _, app := range apps {
+		out = append(out, fmt.Errorf("service %s: %w", app, errors.Join(errs[app]...)))
+	}
+	return errors.Join(out...)
+}
+
+func (b *bundle) add(doc []byte) error {
+	// unstructured decodes JSON numbers as integers
+	j, err := yaml.YAMLToJSON(doc)
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(j); err != nil {
+		return err
+	}
+	if u.GetKind() == "MeshExternalService" {
+		port, _, _ := unstructured.NestedInt64(u.Object, "spec", "match", "port")
+		b.meshExternalServices[key(u.GetNamespace(), u.GetName())] = port
+		return nil
+	}
+	if !scheme.Scheme.Recognizes(u.GroupVersionKind()) {
+		return nil
+	}
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(doc, nil, nil)
+	if err != nil {
+		return fmt.Errorf("%s %s/%s: %w", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+	}
+	b.annotated = append(b.annotated, u)
+	switch o := obj.(type) {
+	case *v1.Service:
+		b.services[key(o.Namespace, o.Name)] = o
+	case *v1.ConfigMap:
+		b.configMaps[key(o.Namespace, o.Name)] = o.Data
+	case *v1.Pod:
+		b.workloads = append(b.workloads, workload{"Pod", o.Namespace, o.Name, v1.PodTemplateSpec{ObjectMeta: o.ObjectMeta, Spec: o.Spec}, nil})
+	case *appsv1.Deployment:
+		b.workloads = append(b.workloads, workload{"Deployment", o.Namespace, o.Name, o.Spec.Template, o.Spec.Selector})
+	case *appsv1.StatefulSet:
+		b.workloads = append(b.workloads, workload{"StatefulSet", o.Namespace, o.Name, o.Spec.Template, o.Spec.Selector})
+	case *appsv1.DaemonSet:
+		b.workloads = append(b.workloads, workload{"DaemonSet", o.Namespace, o.Name, o.Spec.Template, o.Spec.Selector})
+	case *batchv1.Job:
+		b.workloads = append(b.workloads, workload{"Job", o.Namespace, o.Name, o.Spec.Template, nil})
+	case *batchv1.CronJob:
+		b.workloads = append(b.workloads, workload{"CronJob", o.Namespace, o.Name, o.Spec.JobTemplate.Spec.Template, nil})
+	}
+	return nil
+}
+
+func (b *bundle) checkWorkload(w workload) []error {
+	var errs []error
+	if w.kind != "Pod" {
+		// annotations of pods are checked as objects
+		if size := annotationsSize(w.template.Annotations); size > TotalAnnotationSizeLimit {
+			errs = append(errs, fmt.Errorf("annotations of the pod template are %d bytes, over the limit of %d", size, TotalAnnotationSizeLimit))
+		}
+	}
+	if w.selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(w.selector)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid selector: %w", err))
+		} else if !selector.Matches(labels.Set(w.template.Labels)) {
+			errs = append(errs, fmt.Errorf("selector %s doesn't match the labels of its pod template", selector))
+		}
+	}
+	for _, volume := range w.template.Spec.Volumes {
+		if volume.ConfigMap == nil {
+			continue
+		}
+		data, ok := b.configMaps[key(w.namespace, volume.ConfigMap.Name)]
+		if !ok {
+			errs = append(errs, fmt.Errorf("volume %s: no ConfigMap %s/%s", volume.Name, w.namespace, volume.ConfigMap.Name))
+			continue
+		}
+		// config files of other workloads aren't variables
+		vars := map[string]string{}
+		if err := yaml.Unmarshal([]byte(data[configFileKey]), &vars); err == nil && vars[UpstreamURIsEnv] != "" {
+			errs = append(errs, b.checkURIs(w.namespace, vars[UpstreamURIsEnv], fmt.Sprintf("ConfigMap %s %s", volume.ConfigMap.Name, UpstreamURIsEnv))...)
+		}
+	}
+	for _, container := range w.template.Spec.Containers {
+		for _, env := range container.Env {
+			if env.Name != UpstreamURIsEnv || env.Value == "" {
+				continue
+			}
+			errs = append(errs, b.checkURIs(w.namespace, env.Value, UpstreamURIsEnv)...)
+		}
+	}
+	if annotation, ok := w.template.Annotations[metadata.KumaReachableBackends]; ok {
+		errs = append(errs, b.checkReachableBackends(w.namespace, annotation)...)
+	}
+	if annotation, ok := w.template.Annotations[metadata.KumaTransparentProxyingReachableServicesAnnotation]; ok {
+		for _, name := range strings.Split(annotation, ",") {
+			if err := b.checkReachableService(name); err != nil {
+				errs = append(errs, fmt.Errorf("reachable service %s: %w", name, err))
+			}
+		}
+	}
+	return errs
+}
+
+// checkURIs checks the comma separated uris, the errors are prefixed by where they're set.
+func (b *bundle) checkURIs(namespace string, uris string, source string) []error {
+	var errs []error
+	for _, uri := range strings.Split(uris, ",") {
+		if err := b.checkURI(namespace, uri); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", source, uri, err))
+		}
+	}
+	return errs
+}
+
+// checkURI resolves the host of the uri like the DNS of the cluster and the mesh would.
+func (b *bundle) checkURI(namespace string, uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	host, portValue, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portValue)
+	if err != nil {
+		return err
+	}
+	if name, ok := strings.CutSuffix(host, meshExternalServiceDomain); ok {
+		for k, p := range b.meshExternalServices {
+			if strings.HasSuffix(k, "/"+name) {
+				if p != int64(port) {
+					return fmt.Errorf("MeshExternalService %s matches port %d", k, p)
+				}
+				return nil
+			}
+		}
+		return fmt.Errorf("no MeshExternalService %s", name)
+	}
+	// MeshMultiZoneServices and MeshServices synced from other zones aren't generated for this zone
+	if strings.HasSuffix(host, ".mzsvc.mesh.local") || zonedMeshServiceHost.MatchString(host) {
+		return nil
+	}
+	parts := strings.Split(strings.TrimSuffix(strings.TrimSuffix(host, ".svc.cluster.local"), ".svc.mesh.local"), ".")
+	if len(parts) > 2 {
+		return fmt.Errorf("unknown host %s", host)
+	}
+	if len(parts) == 2 {
+		namespace = parts[1]
+	}
+	return b.checkServicePort(namespace, parts[0], port)
+}
+
+func (b *bundle) checkServicePort(namespace string, name string, port int) error {
+	svc, ok := b.services[key(namespace, name)]
+	if !ok {
+		return fmt.Errorf("no Service %s/%s", namespace, name)
+	}
+	for _, p := range svc.Spec.Ports {
+		if int(p.Port) == port {
+			return nil
+		}
+	}
+	return fmt.Errorf("Service %s/%s has no port %d", namespace, name, port)
+}
+
+func (b *bundle) checkReachableBackends(namespace string, annotation string) []error {
+	refs := controllers.ReachableBackendRefs{}
+	if err := json.Unmarshal([]byte(annotation), &refs); err != nil {
+		return []error{fmt.Errorf("reachable backends: %w", err)}
+	}
+	var errs []error
+	for _, ref := range refs.Refs {
+		if ref == nil || ref.Name == nil {
+			continue
+		}
+		ns := namespace
+		if ref.Namespace != nil {
+			ns = *ref.Namespace
+		}
+		var err error
+		switch ref.Kind {
+		case string(v1alpha1.MeshService):
+			if ref.Port != nil {
+				err = b.checkServicePort(ns, *ref.Name, int(*ref.Port))
+			} else if _, ok := b.services[key(ns, *ref.Name)]; !ok {
+				err = fmt.Errorf("no Service %s/%s", ns, *ref.Name)
+			}
+		case string(v1alpha1.MeshExternalService):
+			if _, ok := b.meshExternalServices[key(ns, *ref.Name)]; !ok {
+				err = fmt.Errorf("no MeshExternalService %s/%s", ns, *ref.Name)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reachable backend %s %s: %w", ref.Kind, *ref.Name, err))
+		}
+	}
+	return errs
+}
+
+// checkReachableService resolves a kuma.io/service name, <name>_<namespace>_svc_<port>.
+func (b *bundle) checkReachableService(name string) error {
+	parts := strings.Split(name, "_")
+	if len(parts) != 4 || parts[2] != "svc" {
+		return errors.New("not a name of a Kubernetes service")
+	}
+	port, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return err
+	}
+	return b.checkServicePort(parts[1], parts[0], port)
+}
+
+func (b *bundle) checkSelects(svc *v1.Service) error {
+	if len(svc.Spec.Selector) == 0 {
+		return nil
+	}
+	selector := labels.SelectorFromSet(svc.Spec.Selector)
+	for _, w := range b.workloads {
+		if w.namespace == svc.Namespace && selector.Matches(labels.Set(w.template.Labels)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("selector %s doesn't match any pod", selector)
+}
+
+func appOf(obj metav1.Object, fallback string) string {
+	if app, ok := obj.GetLabels()["app"]; ok {
+		return app
+	}
+	return fallback
+}
+
+func annotationsSize(annotations map[string]string) int {
+	size := 0
+	for k, v := range annotations {
+		size += len(k) + len(v)
+	}
+	return size
+}
+
+func key(namespace string, name string) string {
+	return namespace + "/" + name
+}
@@ -0,0 +1,178 @@
+package validate_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/fakeservice"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/validate"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/workloadapp"
+)
+
+var graph = apis.ServiceGraph{
+	Services: []apis.Service{
+		{Replicas: 1, Edges: []int{1, 2}, Idx: 0},
+		{Replicas: 1, Edges: []int{2, 3}, Idx: 1},
+		{Replicas: 1, Edges: []int{}, Idx: 2},
+		{Replicas: 1, Edges: []int{}, Idx: 3, External: true},
+	},
+}
+
+func generate(t *testing.T, opts ...k8s.Option) string {
+	t.Helper()
+	generator, err := k8s.NewGenerator(opts...)
+	if err != nil {
+		t.Fatal("failed creating a generator", err)
+	}
+	buf := bytes.Buffer{}
+	if err := generator.Apply(&buf, graph); err != nil {
+		t.Fatal("failed generating", err)
+	}
+	return buf.String()
+}
+
+// without removes the object of the kind and name from the manifest.
+func without(manifest string, kind string, name string) string {
+	var out []string
+	for _, doc := range strings.Split(manifest, "---\n") {
+		if strings.Contains(doc, fmt.Sprintf("\nkind: %s\n", kind)) && strings.Contains(doc, fmt.Sprintf("\n  name: %s\n", name)) {
+			continue
+		}
+		out = append(out, doc)
+	}
+	return strings.Join(out, "---\n")
+}
+
+func TestReferences(t *testing.T) {
+	reachableBackends := generate(t, append(
+		fakeservice.GeneratorOpts(fakeservice.WithReachableBackends()),
+		k8s.WithNamespace("foo"),
+	)...)
+	reachableServices := generate(t, append(
+		fakeservice.GeneratorOpts(fakeservice.WithReachableServices()),
+		k8s.WithNamespace("foo"),
+		k8s.WithMeshPassthrough(),
+	)...)
+	meshServiceHostnames := generate(t, append(
+		fakeservice.GeneratorOpts(fakeservice.WithReachableBackends()),
+		k8s.WithNamespace("foo"),
+		k8s.WithFormatters(k8s.MeshServiceFormatters("fake-service", "foo", "")),
+	)...)
+	fqdns := generate(t, append(
+		fakeservice.GeneratorOpts(),
+		k8s.WithNamespace("foo"),
+		k8s.WithFormatters(k8s.FQDNFormatters("fake-service", "foo")),
+	)...)
+	loadGenerator := generate(t, append(
+		fakeservice.GeneratorOpts(),
+		k8s.WithNamespace("foo"),
+		k8s.WithLoadGenerator(k8s.LoadGenerator{Image: "mesh-perf-workload:latest", RPS: 1}),
+	)...)
+	configFiles := generate(t, append(
+		workloadapp.GeneratorOpts(fakeservice.WithConfigFile()),
+		k8s.WithNamespace("foo"),
+	)...)
+	configMaps := generate(t,
+		k8s.WithNamespace("foo"),
+		k8s.WithImage("nginx"),
+		k8s.WithPort(8080),
+		k8s.WithConfigMapGenerator(func(k8s.Formatters, apis.Service) (string, error) {
+			return "", nil
+		}),
+	)
+
+	type testCase struct {
+		desc     string
+		manifest string
+		errs     []string
+	}
+	tests := []testCase{
+		{desc: "reachable backends", manifest: reachableBackends},
+		{desc: "reachable services", manifest: reachableServices},
+		{desc: "config maps", manifest: configMaps},
+		{desc: "config files", manifest: configFiles},
+		{desc: "mesh service hostnames", manifest: meshServiceHostnames},
+		{desc: "cluster hostnames", manifest: fqdns},
+		{desc: "load generator", manifest: loadGenerator},
+		{
+			desc:     "missing Service of a cluster hostname",
+			manifest: without(fqdns, "Service", "fake-service-002"),
+			errs:     []string{"UPSTREAM_URIS http://fake-service-002.foo.svc.cluster.local:9090: no Service foo/fake-service-002"},
+		},
+		{
+			desc:     "missing Service",
+			manifest: without(reachableBackends, "Service", "fake-service-002"),
+			errs: []string{
+				"service fake-service-000: Deployment foo/fake-service-000: UPSTREAM_URIS http://fake-service-002:9090: no Service foo/fake-service-002",
+				"reachable backend MeshService fake-service-002: no Service foo/fake-service-002",
+				"service fake-service-001: Deployment foo/fake-service-001",
+			},
+		},
+		{
+			desc:     "missing MeshExternalService",
+			manifest: without(reachableBackends, "MeshExternalService", "fake-service-003"),
+			errs: []string{
+				"UPSTREAM_URIS http://fake-service-003.extsvc.mesh.local:9090: no MeshExternalService fake-service-003",
+				"reachable backend MeshExternalService fake-service-003: no MeshExternalService kong-mesh-system/fake-service-003",
+			},
+		},
+		{
+			desc:     "missing reachable service",
+			manifest: without(reachableServices, "Service", "fake-service-001"),
+			errs:     []string{"reachable service fake-service-001_foo_svc_9090: no Service foo/fake-service-001"},
+		},
+		{
+			desc:     "missing ConfigMap",
+			manifest: without(configMaps, "ConfigMap", "microservice-001"),
+			errs:     []string{"service microservice-001: Deployment foo/microservice-001: volume config: no ConfigMap foo/microservice-001"},
+		},
+		{
+			desc:     "missing Service of a config file",
+			manifest: without(configFiles, "Service", "fake-service-002"),
+			errs: []string{
+				"service fake-service-000: Deployment foo/fake-service-000: ConfigMap fake-service-000 UPSTREAM_URIS http://fake-service-002:9090: no Service foo/fake-service-002",
+			},
+		},
+		{
+			desc:     "missing pods",
+			manifest: without(configMaps, "Deployment", "microservice-001"),
+			errs:     []string{"service microservice-001: Service foo/microservice-001: selector app=microservice-001 doesn't match any pod"},
+		},
+		{
+			desc: "annotations over the limit",
+			manifest: fmt.Sprintf(`apiVersion: v1
+kind: Pod
+metadata:
+  name: foo
+  namespace: foo
+  labels:
+    app: foo
+  annotations:
+    foo: %s
+`, strings.Repeat("a", validate.TotalAnnotationSizeLimit)),
+			errs: []string{"service foo: foo/foo: annotations are 262147 bytes, over the limit of 262144"},
+		},
+	}
+	for _, tc := range tests {
+		err := validate.References([]byte(tc.manifest))
+		if len(tc.errs) == 0 {
+			if err != nil {
+				t.Errorf("test: %s, expected no error, got: %v", tc.desc, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("test: %s, expected an error", tc.desc)
+			continue
+		}
+		for _, s := range tc.errs {
+			if !strings.Contains(err.Error(), s) {
+				t.Errorf("test: %s, expected an error containing %q, got: %v", tc.desc, s, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package validate
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/applyconfigurations"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kumahq/kuma/v2/api/common/v1alpha1"
+	"github.com/kumahq/kuma/v2/pkg/plugins/runtime/k8s/controllers"
+	"github.com/kumahq/kuma/v2/pkg/plugins/runtime/k8s/metadata"
+)
+
+// crds are the CustomResourceDefinitions of the Kuma and Gateway API resources the generators emit. The Gateway
+// API ones are copied from its module with `make generate-crds`. The Kuma ones are minimal stand-ins, with the
+// fields the generators emit and the confs of policies unchecked, until `make generate-crds` replaces them with
+// the CRDs of the Kuma chart.
+//
+//go:embed crds
+var crds embed.FS
+
+var (
+	decoder       = serializer.NewCodecFactory(scheme.Scheme, serializer.EnableStrict).UniversalDeserializer()
+	typeConverter = applyconfigurations.NewTypeConverter(scheme.Scheme)
+	crdSchemas    = sync.OnceValues(loadCRDSchemas)
+)
+
+// Manifest checks every object of a multi-document YAML manifest, like the output of graph_k8s.Generator,
+// offline: Kubernetes objects against the schemas of client-go and custom resources against the schemas of
+// their embedded CRDs. Unknown fields, objects without a schema and malformed reachable backends
+// annotations are errors. The errors of all the objects are joined.
+func Manifest(manifest []byte) error {
+	var errs []error
+	for i, doc := range documents(manifest) {
+		if err := Object(doc); err != nil {
+			errs = append(errs, fmt.Errorf("document %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// documents splits a multi-document YAML manifest, skipping empty documents.
+func documents(manifest []byte) [][]byte {
+	var out [][]byte
+	for _, doc := range bytes.Split(manifest, []byte("\n---\n")) {
+		doc = bytes.TrimPrefix(doc, []byte("---\n"))
+		if len(bytes.TrimSpace(doc)) != 0 {
+			out = append(out, doc)
+		}
+	}
+	return out
+}
+
+// Object checks a single YAML or JSON object.
+func Object(doc []byte) error {
+	u := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(doc, &u.Object); err != nil {
+		return err
+	}
+	gvk := u.GroupVersionKind()
+	if gvk.Kind == "" || gvk.Version == "" {
+		return errors.New("object must have an apiVersion and a kind")
+	}
+	if err := object(doc, u, gvk); err != nil {
+		return fmt.Errorf("%s %s/%s: %w", gvk.Kind, u.GetNamespace(), u.GetName(), err)
+	}
+	return nil
+}
+
+func object(doc []byte, u *unstructured.Unstructured, gvk schema.GroupVersionKind) error {
+	if !scheme.Scheme.Recognizes(gvk) {
+		return customResource(u, gvk)
+	}
+	obj, _, err := decoder.Decode(doc, nil, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := typeConverter.ObjectToTyped(obj); err != nil {
+		return err
+	}
+	if annotation, ok := podAnnotations(obj)[metadata.KumaReachableBackends]; ok {
+		if err := reachableBackends(annotation); err != nil {
+			return fmt.Errorf("annotation %s: %w", metadata.KumaReachableBackends, err)
+		}
+	}
+	return nil
+}
+
+func customResource(u *unstructured.Unstructured, gvk schema.GroupVersionKind) error {
+	schemas, err := crdSchemas()
+	if err != nil {
+		return err
+	}
+	crdSchema, ok := schemas[gvk]
+	if !ok {
+		return fmt.Errorf("no schema of %s", gvk)
+	}
+	return validate.AgainstSchema(crdSchema, u.Object, strfmt.Default)
+}
+
+// podAnnotations are the annotations of the pods of a workload.
+func podAnnotations(obj runtime.Object) map[string]string {
+	switch o := obj.(type) {
+	case *v1.Pod:
+		return o.Annotations
+	case *appsv1.Deployment:
+		return o.Spec.Template.Annotations
+	case *appsv1.StatefulSet:
+		return o.Spec.Template.Annotations
+	case *appsv1.DaemonSet:
+		return o.Spec.Template.Annotations
+	case *batchv1.Job:
+		return o.Spec.Template.Annotations
+	case *batchv1.CronJob:
+		return o.Spec.JobTemplate.Spec.Template.Annotations
+	}
+	return nil
+}
+
+// reachableBackends checks the annotation is the JSON the control plane reads.
+func reachableBackends(annotation string) error {
+	d := json.NewDecoder(strings.NewReader(annotation))
+	d.DisallowUnknownFields()
+	refs := controllers.ReachableBackendRefs{}
+	if err := d.Decode(&refs); err != nil {
+		return err
+	}
+	kinds := []string{
+		string(v1alpha1.MeshService),
+		string(v1alpha1.MeshExternalService),
+		string(v1alpha1.MeshMultiZoneService),
+	}
+	for i, ref := range refs.Refs {
+		if ref == nil {
+			return fmt.Errorf("ref %d is null", i)
+		}
+		if !slices.Contains(kinds, ref.Kind) {
+			return fmt.Errorf("ref %d has invalid kind %q", i, ref.Kind)
+		}
+		if (ref.Name == nil) == (len(ref.Labels) == 0) {
+			return fmt.Errorf("ref %d must have either a name or labels", i)
+		}
+	}
+	return nil
+}
+
+type customResourceDefinition struct {
+	Spec struct {
+		Group string `json:"group"`
+		Names struct {
+			Kind string `json:"kind"`
+		} `json:"names"`
+		Versions []struct {
+			Name   string `json:"name"`
+			Schema struct {
+				OpenAPIV3Schema spec.Schema `json:"openAPIV3Schema"`
+			} `json:"schema"`
+		} `json:"versions"`
+	} `json:"spec"`
+}
+
+func loadCRDSchemas() (map[schema.GroupVersionKind]*spec.Schema, error) {
+	out := map[schema.GroupVersionKind]*spec.Schema{}
+	err := fs.WalkDir(crds, "crds", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != ".yaml" {
+			return err
+		}
+		b, err := crds.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		crd := customResourceDefinition{}
+		if err := yaml.Unmarshal(b, &crd); err != nil {
+			return fmt.Errorf("failed parsing %s: %w", name, err)
+		}
+		for _, version := range crd.Spec.Versions {
+			s := version.Schema.OpenAPIV3Schema
+			rejectUnknownFields(&s)
+			out[schema.GroupVersionKind{Group: crd.Spec.Group, Version: version.Name, Kind: crd.Spec.Names.Kind}] = &s
+		}
+		return nil
+	})
+	return out, err
+}
+
+// rejectUnknownFields makes objects reject the fields missing from their properties, like the API server
+// does with strict field validation, unless they preserve unknown fields.
+func rejectUnknownFields(s *spec.Schema) {
+	if preserve, _ := s.Extensions.GetBool("x-kubernetes-preserve-unknown-fields"); !preserve &&
+		len(s.Properties) > 0 && s.AdditionalProperties == nil {
+		s.AdditionalProperties = &spec.SchemaOrBool{Allows: false}
+	}
+	for name, property := range s.Properties {
+		rejectUnknownFields(&property)
+		s.Properties[name] = property
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		rejectUnknownFields(s.AdditionalProperties.Schema)
+	}
+	if s.Items != nil {
+		if s.Items.Schema != nil {
+			rejectUnknownFields(s.Items.Schema)
+		}
+		for i := range s.Items.Schemas {
+			rejectUnknownFields(&s.Items.Schemas[i])
+		}
+	}
+	for _, schemas := range [][]spec.Schema{s.AllOf, s.AnyOf, s.OneOf} {
+		for i := range schemas {
+			rejectUnknownFields(&schemas[i])
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package validate_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/gateway"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/fakeservice"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/gatewayapi"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/validate"
+	"github.com/kong/mesh-perf/pkg/graph/generators/policies"
+)
+
+func TestGenerated(t *testing.T) {
+	graph := apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 2, Edges: []int{1, 2}, Idx: 0},
+			{Replicas: 2, Edges: []int{2}, Idx: 1},
+			{Replicas: 2, Edges: []int{}, Idx: 2},
+		},
+	}
+	opts := append(
+		fakeservice.GeneratorOpts(fakeservice.WithReachableBackends()),
+		k8s.WithNamespace("foo"),
+		k8s.WithWorkloadKindFn(func(svc apis.Service) k8s.WorkloadKind {
+			return []k8s.WorkloadKind{k8s.Deployment, k8s.CronJob, k8s.Pod}[svc.Idx]
+		}),
+		k8s.WithLoadGenerator(k8s.LoadGenerator{Image: "mesh-perf-workload:latest", RPS: 1}),
+	)
+	generator, err := k8s.NewGenerator(opts...)
+	if err != nil {
+		t.Fatal("failed creating a generator", err)
+	}
+	kumaGenerator, err := k8s.NewGenerator(append(
+		fakeservice.GeneratorOpts(),
+		k8s.WithNamespace("foo"),
+		k8s.WithHostnameGenerator(),
+		k8s.WithVersions(k8s.Version{Name: "v1", Weight: 90}, k8s.Version{Name: "v2", Weight: 10}),
+	)...)
+	if err != nil {
+		t.Fatal("failed creating a generator", err)
+	}
+	externalGraph := apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1, 2}, Idx: 0},
+			{Replicas: 1, Edges: []int{}, Idx: 1, External: true},
+			{Replicas: 1, Edges: []int{}, Idx: 2, External: true},
+		},
+	}
+	for desc, tc := range map[string]struct {
+		generator apis.Generator
+		graph     *apis.ServiceGraph
+	}{
+		"fake services":  {generator: generator},
+		"http routes":    {generator: gatewayapi.NewGenerator(gatewayapi.WithNamespace("foo"))},
+		"grpc routes":    {generator: gatewayapi.NewGenerator(gatewayapi.WithRouteKind(gatewayapi.GRPCRoute))},
+		"kuma resources": {generator: kumaGenerator},
+		"kuma policies": {generator: policies.NewGenerator(
+			policies.WithServiceNamespace("foo"),
+			policies.WithTrafficPermissions(),
+			policies.WithMeshDefaults(),
+			policies.WithTimeouts(policies.PerEdge, policies.DefaultTimeout),
+		)},
+		"builtin gateway": {generator: gateway.NewGenerator(gateway.WithNamespace("foo"))},
+		"external services": {
+			generator: mustGenerator(t, append(fakeservice.GeneratorOpts(), k8s.WithNamespace("foo"))...),
+			graph:     &externalGraph,
+		},
+		"mesh passthrough": {
+			generator: mustGenerator(t, append(fakeservice.GeneratorOpts(), k8s.WithNamespace("foo"), k8s.WithMeshPassthrough())...),
+			graph:     &externalGraph,
+		},
+	} {
+		g := graph
+		if tc.graph != nil {
+			g = *tc.graph
+		}
+		buf := bytes.Buffer{}
+		if err := tc.generator.Apply(&buf, g); err != nil {
+			t.Fatalf("test: %s, failed generating: %v", desc, err)
+		}
+		if err := validate.Manifest(buf.Bytes()); err != nil {
+			t.Errorf("test: %s, expected a valid manifest, got: %v", desc, err)
+		}
+	}
+}
+
+func mustGenerator(t *testing.T, opts ...k8s.Option) apis.Generator {
+	t.Helper()
+	generator, err := k8s.NewGenerator(opts...)
+	if err != nil {
+		t.Fatal("failed creating a generator", err)
+	}
+	return generator
+}
+
+func TestInvalid(t *testing.T) {
+	for desc, tc := range map[string]struct {
+		manifest string
+		err      string
+	}{
+		"unknown field": {
+			manifest: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: foo
+spec:
+  portz: []
+`,
+			err: `unknown field "spec.portz"`,
+		},
+		"wrong type": {
+			manifest: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+spec:
+  replicas: two
+`,
+			err: "Deployment /foo",
+		},
+		"reachable backends": {
+			manifest: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: foo
+  annotations:
+    kuma.io/reachable-backends: '{"refs":[{"kind":"Service","name":"bar"}]}'
+`,
+			err: `invalid kind "Service"`,
+		},
+		"unknown field of a custom resource": {
+			manifest: `
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: foo
+spec:
+  rulez: []
+`,
+			err: "rulez",
+		},
+		"unknown field of a kuma policy": {
+			manifest: `
+apiVersion: kuma.io/v1alpha1
+kind: MeshTimeout
+metadata:
+  name: foo
+spec:
+  targetRef:
+    kind: Mesh
+  tos: []
+`,
+			err: "tos",
+		},
+		"no schema": {
+			manifest: `
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: foo
+`,
+			err: "no schema of example.com/v1, Kind=Foo",
+		},
+	} {
+		err := validate.Manifest([]byte(strings.TrimPrefix(tc.manifest, "\n")))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("test: %s, expected an error containing %q, got: %v", desc, tc.err, err)
+		}
+	}
+}
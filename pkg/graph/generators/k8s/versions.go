@@ -0,0 +1,100 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const VersionLabel = "version"
+
+// Version is a version every service is deployed with, it receives Weight of the traffic to the service.
+type Version struct {
+	Name   string
+	Weight int
+}
+
+// WithVersions deploys each service as a workload and a Service per version named <name>-<version>, with the
+// version label on their pods. The Service of the service selects every version and a MeshHTTPRoute splits
+// the traffic to it across the versions by their weights. Each version runs the replicas of the service.
+func WithVersions(versions ...Version) Option {
+	return OptionFn(func(g *generator) error {
+		for _, v := range versions {
+			if v.Name == "" {
+				return errors.New("a version must have a name")
+			}
+			if v.Weight < 0 {
+				return fmt.Errorf("invalid weight %d of version %s", v.Weight, v.Name)
+			}
+		}
+		g.versions = versions
+		return nil
+	})
+}
+
+// VersionName is the name of the workload and the Service of a version of a service.
+func VersionName(name string, version Version) string {
+	return fmt.Sprintf("%s-%s", name, version.Name)
+}
+
+func versionedObjectMeta(objectMeta metav1.ObjectMeta, version Version) metav1.ObjectMeta {
+	out := *objectMeta.DeepCopy()
+	out.Name = VersionName(objectMeta.Name, version)
+	out.Labels[VersionLabel] = version.Name
+	return out
+}
+
+// trafficSplit creates the MeshHTTPRoute splitting the traffic to the service across its versions.
+func (g generator) trafficSplit(name string, namespace string) *unstructured.Unstructured {
+	var backendRefs []interface{}
+	for _, v := range g.versions {
+		backendRefs = append(backendRefs, map[string]interface{}{
+			"kind":      "MeshService",
+			"name":      VersionName(name, v),
+			"namespace": namespace,
+			"port":      int64(g.port),
+			"weight":    int64(v.Weight),
+		})
+	}
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "kuma.io/v1alpha1",
+			"kind":       "MeshHTTPRoute",
+			"metadata": map[string]interface{}{
+				"name":      fmt.Sprintf("%s-split", name),
+				"namespace": g.systemNamespace,
+			},
+			"spec": map[string]interface{}{
+				"targetRef": map[string]interface{}{
+					"kind": "Mesh",
+				},
+				"to": []interface{}{
+					map[string]interface{}{
+						"targetRef": map[string]interface{}{
+							"kind":      "MeshService",
+							"name":      name,
+							"namespace": namespace,
+						},
+						"rules": []interface{}{
+							map[string]interface{}{
+								"matches": []interface{}{
+									map[string]interface{}{
+										"path": map[string]interface{}{
+											"type":  "PathPrefix",
+											"value": "/",
+										},
+									},
+								},
+								"default": map[string]interface{}{
+									"backendRefs": backendRefs,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
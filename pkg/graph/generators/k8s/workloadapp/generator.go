@@ -0,0 +1,30 @@
+package workloadapp
+
+import (
+	"fmt"
+
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/fakeservice"
+)
+
+// ImageName is the image of tools/workload, pushed to the registry with `make ecr/push/mesh-perf-workload`.
+const ImageName = fakeservice.WorkloadImageName
+
+// GeneratorOpts are the options of fakeservice.GeneratorOpts running the workload of pkg/workload instead of
+// fake-service. The workload reads the same config, so the options of fakeservice apply, and it isn't on a public
+// registry so it's pulled from the registry of fakeservice.WithRegistry. It reads the config file of
+// fakeservice.WithConfigFile, and it only serves HTTP, generating services with fakeservice.WithGRPC fails.
+func GeneratorOpts(fns ...fakeservice.OptionFn) []k8s.Option {
+	fns = append([]fakeservice.OptionFn{fakeservice.WithImageName(ImageName)}, fns...)
+	return fakeservice.GeneratorOpts(append(fns, fakeservice.WithHTTPOnly())...)
+}
+
+// WithLoadGenerator deploys the load generator of the workload image in the registry, calling every root of the
+// graph at rps.
+func WithLoadGenerator(registry string, rps float64) k8s.Option {
+	image := ImageName
+	if registry != "" {
+		image = fmt.Sprintf("%s/%s", registry, ImageName)
+	}
+	return k8s.WithLoadGenerator(k8s.LoadGenerator{Image: image, RPS: rps})
+}
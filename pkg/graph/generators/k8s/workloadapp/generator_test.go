@@ -0,0 +1,59 @@
+package workloadapp_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/fakeservice"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/workloadapp"
+)
+
+func TestGeneratorOpts(t *testing.T) {
+	type testCase struct {
+		desc  string
+		fns   []fakeservice.OptionFn
+		image string
+	}
+	tests := []testCase{
+		{desc: "default", image: "image: mesh-perf-workload:latest"},
+		{
+			desc:  "registry",
+			fns:   []fakeservice.OptionFn{fakeservice.WithRegistry("registry.example.com"), fakeservice.WithReachableBackends()},
+			image: "image: registry.example.com/mesh-perf-workload:latest",
+		},
+	}
+	for _, tc := range tests {
+		generator, err := k8s.NewGenerator(append(workloadapp.GeneratorOpts(tc.fns...), k8s.WithNamespace("foo"))...)
+		if err != nil {
+			t.Fatal("failed", err)
+		}
+		buf := bytes.Buffer{}
+		err = generator.Apply(&buf, apis.ServiceGraph{
+			Services: []apis.Service{
+				{Replicas: 1, Edges: []int{1}, Idx: 0},
+				{Replicas: 1, Edges: []int{}, Idx: 1},
+			},
+		})
+		if err != nil {
+			t.Fatal("failed", err)
+		}
+		out := buf.String()
+		for _, s := range []string{tc.image, "value: http://fake-service-001:9090", "path: /ready"} {
+			if !strings.Contains(out, s) {
+				t.Errorf("test: %s, expected output to contain %q, got:\n%s", tc.desc, s, out)
+			}
+		}
+	}
+
+	generator, err := k8s.NewGenerator(workloadapp.GeneratorOpts(fakeservice.WithGRPC())...)
+	if err != nil {
+		t.Fatal("failed", err)
+	}
+	err = generator.Apply(&bytes.Buffer{}, apis.ServiceGraph{Services: []apis.Service{{Replicas: 1, Edges: []int{}, Idx: 0}}})
+	if err == nil || !strings.Contains(err.Error(), "only serves HTTP") {
+		t.Errorf("expected gRPC services of the workload to fail, got: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package k8s
+
+import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+)
+
+// WorkloadKind is the kind of the workload running the pods of a service.
+type WorkloadKind string
+
+const (
+	Deployment  WorkloadKind = "Deployment"
+	StatefulSet WorkloadKind = "StatefulSet"
+	// DaemonSet runs a pod of the service on every node, regardless of its replicas.
+	DaemonSet WorkloadKind = "DaemonSet"
+	// Job runs the replicas of the service once, for the job lifetime. The services serve until they're
+	// stopped, so their pods never exit on their own: the job ends failed when its ActiveDeadlineSeconds is
+	// reached, and its pods are gone. The pod template of a Job is immutable, applying a changed graph fails
+	// until the Job is deleted.
+	Job WorkloadKind = "Job"
+	// CronJob runs the replicas of the service every minute, for the job lifetime, so their
+	// proxies register and deregister all the time. Like Jobs, each run ends only at its deadline.
+	CronJob WorkloadKind = "CronJob"
+	// Pod runs the replicas of the service as bare pods named <name>-<replica>.
+	Pod WorkloadKind = "Pod"
+)
+
+// DefaultJobLifetime is how long the pods of jobs run, a CronJob starts new ones every minute.
+const DefaultJobLifetime = 50 * time.Second
+
+// CronJobSchedule is the schedule of the CronJobs of services.
+const CronJobSchedule = "*/1 * * * *"
+
+// WithWorkloadKind sets the same kind of workload for every service.
+func WithWorkloadKind(kind WorkloadKind) Option {
+	return WithWorkloadKindFn(func(apis.Service) WorkloadKind {
+		return kind
+	})
+}
+
+// WithWorkloadKindFn sets the kind of workload of each service.
+func WithWorkloadKindFn(fn func(svc apis.Service) WorkloadKind) Option {
+	return OptionFn(func(g *generator) error {
+		g.workloadKind = fn
+		return nil
+	})
+}
+
+// WithJobLifetime sets how long the pods of Jobs and CronJobs run before they're stopped, it's their
+// ActiveDeadlineSeconds as their pods never exit.
+func WithJobLifetime(lifetime time.Duration) Option {
+	return OptionFn(func(g *generator) error {
+		if lifetime < time.Second {
+			return fmt.Errorf("invalid job lifetime %s", lifetime)
+		}
+		g.jobLifetime = lifetime
+		return nil
+	})
+}
+
+// workload creates the workloads of the kind running the pods, selected by the selector labels of its metadata.
+func (g generator) workload(kind WorkloadKind, objectMeta metav1.ObjectMeta, replicas int, podTemplateSpec v1.PodTemplateSpec) []runtime.Object {
+	repl := int32(replicas)
+	selector := &metav1.LabelSelector{
+		MatchLabels: selectorLabels(objectMeta.Labels),
+	}
+	var out runtime.Object
+	switch kind {
+	case StatefulSet:
+		sts := &appsv1.StatefulSet{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "StatefulSet",
+				APIVersion: "apps/v1",
+			},
+			Spec: appsv1.StatefulSetSpec{
+				ServiceName: g.statefulSetServiceName(objectMeta),
+				Replicas:    &repl,
+				Selector:    selector,
+				Template:    podTemplateSpec,
+			},
+		}
+		objectMeta.DeepCopyInto(&sts.ObjectMeta)
+		out = sts
+	case DaemonSet:
+		ds := &appsv1.DaemonSet{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "DaemonSet",
+				APIVersion: "apps/v1",
+			},
+			Spec: appsv1.DaemonSetSpec{
+				Selector: selector,
+				Template: podTemplateSpec,
+			},
+		}
+		objectMeta.DeepCopyInto(&ds.ObjectMeta)
+		out = ds
+	case Job:
+		job := &batchv1.Job{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Job",
+				APIVersion: "batch/v1",
+			},
+			Spec: g.jobSpec(replicas, podTemplateSpec),
+		}
+		objectMeta.DeepCopyInto(&job.ObjectMeta)
+		out = job
+	case CronJob:
+		cronJob := &batchv1.CronJob{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "CronJob",
+				APIVersion: "batch/v1",
+			},
+			Spec: batchv1.CronJobSpec{
+				Schedule:          CronJobSchedule,
+				ConcurrencyPolicy: batchv1.ReplaceConcurrent,
+				JobTemplate: batchv1.JobTemplateSpec{
+					Spec: g.jobSpec(replicas, podTemplateSpec),
+				},
+			},
+		}
+		objectMeta.DeepCopyInto(&cronJob.ObjectMeta)
+		out = cronJob
+	case Pod:
+		var pods []runtime.Object
+		for i := 0; i < replicas; i++ {
+			pod := &v1.Pod{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "Pod",
+					APIVersion: "v1",
+				},
+				Spec: *podTemplateSpec.Spec.DeepCopy(),
+			}
+			podTemplateSpec.ObjectMeta.DeepCopyInto(&pod.ObjectMeta)
+			pod.Name = fmt.Sprintf("%s-%d", objectMeta.Name, i)
+			pods = append(pods, pod)
+		}
+		return pods
+	default:
+		surge := intstr.FromString("25%")
+		deployment := &appsv1.Deployment{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Deployment",
+				APIVersion: "apps/v1",
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &repl,
+				Strategy: appsv1.DeploymentStrategy{
+					Type: appsv1.RollingUpdateDeploymentStrategyType,
+					RollingUpdate: &appsv1.RollingUpdateDeployment{
+						MaxSurge:       &surge,
+						MaxUnavailable: &surge,
+					},
+				},
+				Selector: selector,
+				Template: podTemplateSpec,
+			},
+		}
+		objectMeta.DeepCopyInto(&deployment.ObjectMeta)
+		out = deployment
+	}
+	return []runtime.Object{out}
+}
+
+// jobSpec runs the replicas in parallel until the job lifetime stops them. The deadline is the only end of
+// the job, it's reported as failed with DeadlineExceeded and nothing is retried.
+func (g generator) jobSpec(replicas int, podTemplateSpec v1.PodTemplateSpec) batchv1.JobSpec {
+	repl := int32(replicas)
+	lifetime := int64(g.jobLifetime.Seconds())
+	backoffLimit := int32(0)
+	template := *podTemplateSpec.DeepCopy()
+	template.Spec.RestartPolicy = v1.RestartPolicyNever
+	return batchv1.JobSpec{
+		Parallelism:           &repl,
+		ActiveDeadlineSeconds: &lifetime,
+		BackoffLimit:          &backoffLimit,
+		Template:              template,
+	}
+}
@@ -0,0 +1,439 @@
+package policies
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	kube_meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	meshcircuitbreaker_api "github.com/kumahq/kuma/v2/pkg/plugins/policies/meshcircuitbreaker/api/v1alpha1"
+	meshretry_api "github.com/kumahq/kuma/v2/pkg/plugins/policies/meshretry/api/v1alpha1"
+	meshtimeout_api "github.com/kumahq/kuma/v2/pkg/plugins/policies/meshtimeout/api/v1alpha1"
+	"github.com/kumahq/kuma/v2/pkg/util/pointer"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+)
+
+// TargetRefStyle decides how the top level targetRef of a policy selects the proxies of a service.
+type TargetRefStyle string
+
+const (
+	// TargetMesh selects every proxy of the mesh.
+	TargetMesh TargetRefStyle = "Mesh"
+	// TargetMeshService selects the proxies of the MeshService of the service.
+	TargetMeshService TargetRefStyle = "MeshService"
+	// TargetDataplane selects the proxies by the labels of the service's pods.
+	TargetDataplane TargetRefStyle = "Dataplane"
+)
+
+// Scope decides whether a policy is generated per service or per edge of the graph.
+type Scope string
+
+const (
+	// PerService generates a policy for each service configuring all its outgoing traffic.
+	PerService Scope = "Service"
+	// PerEdge generates a policy for each edge configuring the traffic from a service to its upstream.
+	PerEdge Scope = "Edge"
+)
+
+var (
+	DefaultTimeout = meshtimeout_api.Conf{
+		ConnectionTimeout: duration(5 * time.Second),
+		IdleTimeout:       duration(time.Hour),
+		Http: &meshtimeout_api.Http{
+			RequestTimeout:    duration(15 * time.Second),
+			StreamIdleTimeout: duration(30 * time.Minute),
+		},
+	}
+	DefaultRetry = meshretry_api.Conf{
+		HTTP: &meshretry_api.HTTP{
+			NumRetries:    pointer.To[uint32](3),
+			PerTryTimeout: duration(5 * time.Second),
+			BackOff: &meshretry_api.BackOff{
+				BaseInterval: duration(50 * time.Millisecond),
+				MaxInterval:  duration(500 * time.Millisecond),
+			},
+		},
+	}
+	DefaultCircuitBreaker = meshcircuitbreaker_api.Conf{
+		ConnectionLimits: &meshcircuitbreaker_api.ConnectionLimits{
+			MaxConnections:     pointer.To[uint32](1024),
+			MaxPendingRequests: pointer.To[uint32](1024),
+			MaxRequests:        pointer.To[uint32](1024),
+		},
+		OutlierDetection: &meshcircuitbreaker_api.OutlierDetection{
+			Disabled: pointer.To(false),
+			Detectors: &meshcircuitbreaker_api.Detectors{
+				TotalFailures: &meshcircuitbreaker_api.DetectorTotalFailures{
+					Consecutive: pointer.To[uint32](5),
+				},
+			},
+		},
+	}
+)
+
+// OutboundConf is the typed conf of the policies configuring the outgoing traffic of services.
+type OutboundConf interface {
+	meshtimeout_api.Conf | meshretry_api.Conf | meshcircuitbreaker_api.Conf
+}
+
+// kindOf is the kind of the policies of an OutboundConf.
+func kindOf(conf interface{}) string {
+	switch conf.(type) {
+	case meshtimeout_api.Conf:
+		return "MeshTimeout"
+	case meshretry_api.Conf:
+		return "MeshRetry"
+	case meshcircuitbreaker_api.Conf:
+		return "MeshCircuitBreaker"
+	}
+	panic(fmt.Sprintf("unexpected conf %T", conf))
+}
+
+func duration(d time.Duration) *kube_meta.Duration {
+	return &kube_meta.Duration{Duration: d}
+}
+
+type outbound struct {
+	kind  string
+	scope Scope
+	conf  interface{}
+}
+
+type targeted struct {
+	kind        string
+	conf        interface{}
+	first, last int
+}
+
+type Options struct {
+	namespace          string
+	serviceNamespace   string
+	mesh               string
+	formatters         k8s.Formatters
+	style              TargetRefStyle
+	trafficPermissions bool
+	allowAll           bool
+	meshDefaults       bool
+	outbounds          []outbound
+	targeted           []targeted
+	graph              *apis.ServiceGraph
+}
+
+type OptionFn func(Options) Options
+
+// WithNamespace sets the namespace of the policies, by default it's the system namespace.
+func WithNamespace(name string) OptionFn {
+	return func(o Options) Options {
+		o.namespace = name
+		return o
+	}
+}
+
+// WithServiceNamespace sets the namespace of the services of the graph that don't set one, used to refer to
+// their MeshServices.
+func WithServiceNamespace(name string) OptionFn {
+	return func(o Options) Options {
+		o.serviceNamespace = name
+		return o
+	}
+}
+
+func WithMesh(name string) OptionFn {
+	return func(o Options) Options {
+		o.mesh = name
+		return o
+	}
+}
+
+// WithFormatters sets the formatters used to name services, they must match the ones of the workloads.
+func WithFormatters(f k8s.Formatters) OptionFn {
+	return func(o Options) Options {
+		o.formatters = f
+		return o
+	}
+}
+
+func WithTargetRefStyle(style TargetRefStyle) OptionFn {
+	return func(o Options) Options {
+		o.style = style
+		return o
+	}
+}
+
+// WithTrafficPermissions generates a MeshTrafficPermission allowing the traffic of each edge.
+func WithTrafficPermissions() OptionFn {
+	return func(o Options) Options {
+		o.trafficPermissions = true
+		return o
+	}
+}
+
+// WithAllowAll generates a mesh wide MeshTrafficPermission allowing all the traffic of the mesh, the one of
+// proxies outside the graph, like the load generator, included. A mesh with mTLS denies traffic without it.
+func WithAllowAll() OptionFn {
+	return func(o Options) Options {
+		o.allowAll = true
+		return o
+	}
+}
+
+// WithMeshDefaults generates mesh wide MeshTimeout, MeshRetry and MeshCircuitBreaker with the default confs.
+func WithMeshDefaults() OptionFn {
+	return func(o Options) Options {
+		o.meshDefaults = true
+		return o
+	}
+}
+
+func WithTimeouts(scope Scope, conf meshtimeout_api.Conf) OptionFn {
+	return withOutbound(scope, conf)
+}
+
+func WithRetries(scope Scope, conf meshretry_api.Conf) OptionFn {
+	return withOutbound(scope, conf)
+}
+
+func WithCircuitBreakers(scope Scope, conf meshcircuitbreaker_api.Conf) OptionFn {
+	return withOutbound(scope, conf)
+}
+
+// WithTargeted generates the policies from first to last (excluded) of an ordered set of policies of the kind of
+// the conf. The i-th policy configures the outbounds of the service i modulo the number of services, so growing
+// sets spread over the whole graph and can be applied on top of each other in batches.
+func WithTargeted[C OutboundConf](conf C, first, last int) OptionFn {
+	return func(o Options) Options {
+		o.targeted = append(o.targeted, targeted{kind: kindOf(conf), conf: conf, first: first, last: last})
+		return o
+	}
+}
+
+// TargetedServices returns the indexes of the services configured by the policies from first to last (excluded) of WithTargeted.
+func TargetedServices(svcs apis.ServiceGraph, first, last int) []int {
+	var out []int
+	if len(svcs.Services) == 0 {
+		return out
+	}
+	seen := map[int]struct{}{}
+	for i := first; i < last; i++ {
+		idx := i % len(svcs.Services)
+		if _, ok := seen[idx]; ok {
+			continue
+		}
+		seen[idx] = struct{}{}
+		out = append(out, idx)
+	}
+	return out
+}
+
+func withOutbound(scope Scope, conf interface{}) OptionFn {
+	return func(o Options) Options {
+		o.outbounds = append(o.outbounds, outbound{kind: kindOf(conf), scope: scope, conf: conf})
+		return o
+	}
+}
+
+// NewGenerator returns a generator of the Kuma policies derived from a service graph.
+// Mesh wide policies are generated once, others for every service or edge of the graph.
+func NewGenerator(fns ...OptionFn) k8s.Generator {
+	opts := Options{
+		namespace:  k8s.DefaultSystemNamespace,
+		mesh:       "default",
+		formatters: k8s.SimpleFormatters("microservice"),
+		style:      TargetDataplane,
+	}
+	for _, fn := range fns {
+		if fn != nil {
+			opts = fn(opts)
+		}
+	}
+	return k8s.Generator{
+		Serializer:        k8s.DefaultSerializer,
+		CommonSetup:       k8s.CommonSetupFn(opts.meshWide),
+		WorkloadGenerator: opts,
+	}
+}
+
+// ForGraph binds the options to the graph, so MeshServices are referred to in the namespaces of their services.
+func (o Options) ForGraph(svcs apis.ServiceGraph) k8s.WorkloadGenerator {
+	o.graph = &svcs
+	return o
+}
+
+// Apply generates the policies of the service.
+func (o Options) Apply(svc apis.Service) ([]runtime.Object, []byte, error) {
+	return o.service(svc)
+}
+
+// targetRef selects the proxies of the service with the given style.
+func (o Options) targetRef(idx int) (TargetRef, error) {
+	switch o.style {
+	case TargetMesh:
+		return TargetRef{Kind: "Mesh"}, nil
+	case TargetMeshService:
+		return o.meshServiceRef(idx)
+	default:
+		return TargetRef{
+			Kind:   "Dataplane",
+			Labels: map[string]string{"app": o.formatters.Name(idx)},
+		}, nil
+	}
+}
+
+// meshServiceRef refers to the MeshService of the service, in the namespace of the service in the graph or the
+// service namespace. A ref without a namespace would be resolved in the namespace of the policy.
+func (o Options) meshServiceRef(idx int) (TargetRef, error) {
+	namespace := o.serviceNamespace
+	if o.graph != nil && idx < len(o.graph.Services) && o.graph.Services[idx].Namespace != "" {
+		namespace = o.graph.Services[idx].Namespace
+	}
+	if namespace == "" {
+		return TargetRef{}, errors.New("MeshServices are referred to in the namespace of their service, set it with WithServiceNamespace")
+	}
+	return TargetRef{
+		Kind:      "MeshService",
+		Name:      o.formatters.Name(idx),
+		Namespace: namespace,
+	}, nil
+}
+
+func (o Options) meshWide(svcs apis.ServiceGraph) ([]runtime.Object, []byte, error) {
+	o.graph = &svcs
+	var out []runtime.Object
+	for _, t := range o.targeted {
+		if len(svcs.Services) == 0 {
+			break
+		}
+		for i := t.first; i < t.last; i++ {
+			obj, err := o.outboundPolicy(
+				outbound{kind: t.kind, conf: t.conf},
+				fmt.Sprintf("%s-%05d", kebab(t.kind), i),
+				i%len(svcs.Services),
+				TargetRef{Kind: "Mesh"},
+			)
+			if err != nil {
+				return nil, nil, err
+			}
+			out = append(out, obj)
+		}
+	}
+	if o.allowAll {
+		obj, err := New("MeshTrafficPermission", "allow-all", o.namespace, o.mesh, Spec{
+			TargetRef: &TargetRef{Kind: "Mesh"},
+			From: []From{
+				{TargetRef: TargetRef{Kind: "Mesh"}, Default: trafficPermissionConf{Action: "Allow"}},
+			},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		out = append(out, obj)
+	}
+	if !o.meshDefaults {
+		return out, nil, nil
+	}
+	for _, p := range []outbound{
+		{kind: "MeshTimeout", conf: DefaultTimeout},
+		{kind: "MeshRetry", conf: DefaultRetry},
+		{kind: "MeshCircuitBreaker", conf: DefaultCircuitBreaker},
+	} {
+		obj, err := New(p.kind, fmt.Sprintf("mesh-%s", kebab(p.kind)), o.namespace, o.mesh, Spec{
+			TargetRef: &TargetRef{Kind: "Mesh"},
+			To: []To{
+				{TargetRef: TargetRef{Kind: "Mesh"}, Default: p.conf},
+			},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		out = append(out, obj)
+	}
+	return out, nil, nil
+}
+
+func (o Options) service(svc apis.Service) ([]runtime.Object, []byte, error) {
+	var out []runtime.Object
+	name := o.formatters.Name(svc.Idx)
+	if o.trafficPermissions {
+		for _, edge := range svc.Edges {
+			targetRef, err := o.targetRef(edge)
+			if err != nil {
+				return nil, nil, err
+			}
+			obj, err := New("MeshTrafficPermission", fmt.Sprintf("%s-from-%s", o.formatters.Name(edge), name), o.namespace, o.mesh, Spec{
+				TargetRef: &targetRef,
+				From: []From{
+					{
+						TargetRef: TargetRef{Kind: "MeshSubset", Tags: map[string]string{"app": name}},
+						Default:   trafficPermissionConf{Action: "Allow"},
+					},
+				},
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			out = append(out, obj)
+		}
+	}
+	for _, p := range o.outbounds {
+		switch p.scope {
+		case PerEdge:
+			for _, edge := range svc.Edges {
+				to, err := o.meshServiceRef(edge)
+				if err != nil {
+					return nil, nil, err
+				}
+				obj, err := o.outboundPolicy(p, fmt.Sprintf("%s-to-%s", name, o.formatters.Name(edge)), svc.Idx, to)
+				if err != nil {
+					return nil, nil, err
+				}
+				out = append(out, obj)
+			}
+		case PerService:
+			obj, err := o.outboundPolicy(p, name, svc.Idx, TargetRef{Kind: "Mesh"})
+			if err != nil {
+				return nil, nil, err
+			}
+			out = append(out, obj)
+		default:
+			return nil, nil, fmt.Errorf("unknown scope %q of %s", p.scope, p.kind)
+		}
+	}
+	return out, nil, nil
+}
+
+func (o Options) outboundPolicy(p outbound, name string, idx int, to TargetRef) (runtime.Object, error) {
+	targetRef, err := o.targetRef(idx)
+	if err != nil {
+		return nil, err
+	}
+	return New(p.kind, name, o.namespace, o.mesh, Spec{
+		TargetRef: &targetRef,
+		To: []To{
+			{TargetRef: to, Default: p.conf},
+		},
+	})
+}
+
+// trafficPermissionConf is the conf of MeshTrafficPermissions.
+type trafficPermissionConf struct {
+	Action string `json:"action"`
+}
+
+// kebab turns a kind like MeshCircuitBreaker into circuit-breaker.
+func kebab(kind string) string {
+	var out []rune
+	for i, r := range kind[len("Mesh"):] {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				out = append(out, '-')
+			}
+			r += 'a' - 'A'
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
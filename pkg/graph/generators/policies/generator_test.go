@@ -0,0 +1,166 @@
+package policies_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/policies"
+)
+
+func TestGenerator(t *testing.T) {
+	graph := apis.ServiceGraph{
+		Services: []apis.Service{
+			{Replicas: 1, Edges: []int{1, 2}, Idx: 0},
+			{Replicas: 1, Edges: []int{2}, Idx: 1},
+			{Replicas: 1, Edges: []int{}, Idx: 2},
+		},
+	}
+	type testCase struct {
+		desc     string
+		opts     []policies.OptionFn
+		graph    *apis.ServiceGraph
+		err      string
+		expected map[string]int
+		contains []string
+	}
+	tests := []testCase{
+		{
+			desc: "traffic permissions per edge",
+			opts: []policies.OptionFn{policies.WithTrafficPermissions()},
+			expected: map[string]int{
+				"kind: MeshTrafficPermission": 3,
+			},
+			contains: []string{
+				"name: microservice-002-from-microservice-001",
+				"kind: Dataplane",
+			},
+		},
+		{
+			desc: "allow all",
+			opts: []policies.OptionFn{policies.WithAllowAll()},
+			expected: map[string]int{
+				"kind: MeshTrafficPermission": 1,
+				"kind: Mesh\n":                2,
+			},
+			contains: []string{
+				"name: allow-all",
+				"action: Allow",
+			},
+		},
+		{
+			desc: "timeouts per service and retries per edge",
+			opts: []policies.OptionFn{
+				policies.WithTargetRefStyle(policies.TargetMeshService),
+				policies.WithServiceNamespace("foo"),
+				policies.WithTimeouts(policies.PerService, policies.DefaultTimeout),
+				policies.WithRetries(policies.PerEdge, policies.DefaultRetry),
+			},
+			expected: map[string]int{
+				"kind: MeshTimeout": 3,
+				"kind: MeshRetry":   3,
+			},
+			contains: []string{
+				"name: microservice-000-to-microservice-002",
+				"namespace: foo",
+				"requestTimeout: 15s",
+				"numRetries: 3",
+			},
+		},
+		{
+			desc: "circuit breakers per edge in the namespaces of the graph",
+			opts: []policies.OptionFn{
+				policies.WithCircuitBreakers(policies.PerEdge, policies.DefaultCircuitBreaker),
+			},
+			graph: &apis.ServiceGraph{
+				Services: []apis.Service{
+					{Replicas: 1, Edges: []int{1}, Idx: 0, Namespace: "foo"},
+					{Replicas: 1, Edges: []int{}, Idx: 1, Namespace: "bar"},
+				},
+			},
+			expected: map[string]int{
+				"kind: MeshCircuitBreaker": 1,
+				"namespace: bar":           1,
+			},
+			contains: []string{
+				"consecutive: 5",
+			},
+		},
+		{
+			desc: "edges without a service namespace",
+			opts: []policies.OptionFn{
+				policies.WithRetries(policies.PerEdge, policies.DefaultRetry),
+			},
+			err: "WithServiceNamespace",
+		},
+		{
+			desc: "unknown scope",
+			opts: []policies.OptionFn{
+				policies.WithTimeouts("Zone", policies.DefaultTimeout),
+			},
+			err: `unknown scope "Zone"`,
+		},
+		{
+			desc: "mesh defaults",
+			opts: []policies.OptionFn{policies.WithMeshDefaults()},
+			expected: map[string]int{
+				"kind: MeshTimeout":        1,
+				"kind: MeshRetry":          1,
+				"kind: MeshCircuitBreaker": 1,
+			},
+			contains: []string{
+				"name: mesh-circuit-breaker",
+				"kuma.io/mesh: default",
+			},
+		},
+	}
+	for _, tc := range tests {
+		g := graph
+		if tc.graph != nil {
+			g = *tc.graph
+		}
+		buf := bytes.Buffer{}
+		err := policies.NewGenerator(tc.opts...).Apply(&buf, g)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("test: %s, expected error %q, got: %v", tc.desc, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test: %s, failed: %v", tc.desc, err)
+		}
+		out := buf.String()
+		for s, count := range tc.expected {
+			if got := strings.Count(out, s); got != count {
+				t.Errorf("test: %s, expected %d of %q, got: %d", tc.desc, count, s, got)
+			}
+		}
+		for _, s := range tc.contains {
+			if !strings.Contains(out, s) {
+				t.Errorf("test: %s, expected output to contain %q, got:\n%s", tc.desc, s, out)
+			}
+		}
+	}
+}
+
+func TestTargeted(t *testing.T) {
+	graph := apis.GenerateRandomMesh(1, 4, 50, 1, 1)
+	buf := bytes.Buffer{}
+	generator := policies.NewGenerator(policies.WithTargeted(policies.DefaultTimeout, 2, 7))
+	if err := generator.Apply(&buf, graph); err != nil {
+		t.Fatal("failed", err)
+	}
+	out := buf.String()
+	if got := strings.Count(out, "kind: MeshTimeout"); got != 5 {
+		t.Errorf("expected 5 policies, got: %d", got)
+	}
+	if !strings.Contains(out, "name: timeout-00002") || !strings.Contains(out, "name: timeout-00006") {
+		t.Errorf("expected policies from 2 to 6, got:\n%s", out)
+	}
+	if got := policies.TargetedServices(graph, 2, 7); !reflect.DeepEqual([]int{2, 3, 0, 1}, got) {
+		t.Errorf("expected targeted services [2 3 0 1], got: %v", got)
+	}
+}
@@ -0,0 +1,70 @@
+package policies
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const KumaMeshLabel = "kuma.io/mesh"
+
+// Conf is an untyped configuration, like the rules of a route.
+type Conf map[string]interface{}
+
+type TargetRef struct {
+	Kind        string            `json:"kind"`
+	Name        string            `json:"name,omitempty"`
+	Namespace   string            `json:"namespace,omitempty"`
+	SectionName string            `json:"sectionName,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+// From and To configure the traffic from and to the targetRef with the Default of the policy, the typed
+// conf of its kind, like meshtimeout_api.Conf.
+type From struct {
+	TargetRef TargetRef   `json:"targetRef"`
+	Default   interface{} `json:"default"`
+}
+
+type To struct {
+	TargetRef TargetRef   `json:"targetRef"`
+	Default   interface{} `json:"default,omitempty"`
+	Rules     []Conf      `json:"rules,omitempty"`
+}
+
+// Spec is the spec of a targetRef policy.
+type Spec struct {
+	TargetRef *TargetRef  `json:"targetRef,omitempty"`
+	From      []From      `json:"from,omitempty"`
+	To        []To        `json:"to,omitempty"`
+	Default   interface{} `json:"default,omitempty"`
+}
+
+// New creates a Kuma resource of the given kind in the mesh. The spec is serialized to JSON,
+// so it can be a Spec or any other struct for resources that aren't targetRef policies.
+func New(kind, name, namespace, mesh string, spec interface{}) (*unstructured.Unstructured, error) {
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	content := map[string]interface{}{}
+	if err := json.Unmarshal(b, &content); err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": content,
+		},
+	}
+	obj.SetAPIVersion("kuma.io/v1alpha1")
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	if mesh != "" {
+		obj.SetLabels(map[string]string{
+			KumaMeshLabel: mesh,
+		})
+	}
+	return obj, nil
+}
@@ -0,0 +1,283 @@
+package universal
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/files"
+)
+
+const (
+	// inboundPort is the port kuma-dp exposes the application on, every replica has its own network namespace.
+	inboundPort = 10000
+	// outboundPortBase is the first port of outbounds, the outbound to a service listens on outboundPortBase + idx.
+	outboundPortBase = 20000
+)
+
+type Options struct {
+	baseName          string
+	mesh              string
+	port              int
+	kumaImageRegistry string
+	kumaVersion       string
+	image             string
+	tokenValidity     string
+}
+
+type OptionFn func(Options) Options
+
+func WithBaseName(baseName string) OptionFn {
+	return func(o Options) Options {
+		o.baseName = baseName
+		return o
+	}
+}
+
+func WithMesh(mesh string) OptionFn {
+	return func(o Options) Options {
+		o.mesh = mesh
+		return o
+	}
+}
+
+func WithKumaImageRegistry(registry string) OptionFn {
+	return func(o Options) Options {
+		if registry != "" {
+			o.kumaImageRegistry = registry
+		}
+		return o
+	}
+}
+
+func WithKumaVersion(version string) OptionFn {
+	return func(o Options) Options {
+		o.kumaVersion = version
+		return o
+	}
+}
+
+func WithImage(image string) OptionFn {
+	return func(o Options) Options {
+		o.image = image
+		return o
+	}
+}
+
+// Generator generates a Kuma Universal mode deployment of a service graph: a Dataplane resource
+// for every replica, a docker-compose file running fake-service next to kuma-dp with a Postgres backed
+// control plane and a script bootstrapping dataplane tokens.
+type Generator struct {
+	opts Options
+}
+
+func NewGenerator(fns ...OptionFn) Generator {
+	opts := Options{
+		baseName:          "fake-service",
+		mesh:              "default",
+		port:              9090,
+		kumaImageRegistry: "kong",
+		kumaVersion:       "${KUMA_VERSION}",
+		image:             "nicholasjackson/fake-service:v0.26.0",
+		tokenValidity:     "24h",
+	}
+	for _, fn := range fns {
+		if fn != nil {
+			opts = fn(opts)
+		}
+	}
+	return Generator{opts: opts}
+}
+
+// ApplyDir writes the deployment of the graph to dir.
+func (g Generator) ApplyDir(dir string, svc apis.ServiceGraph) error {
+	out, err := g.Files(svc)
+	if err != nil {
+		return err
+	}
+	return out.Write(dir)
+}
+
+func (g Generator) Files(svc apis.ServiceGraph) (files.Files, error) {
+	if err := svc.Validate(); err != nil {
+		return nil, err
+	}
+	out := files.Files{}
+	compose := g.controlPlane()
+	for _, s := range svc.Services {
+		for replica := 0; replica < s.Replicas; replica++ {
+			name := g.instanceName(s.Idx, replica)
+			dp, err := yaml.Marshal(g.dataplane(s, name))
+			if err != nil {
+				return nil, err
+			}
+			out[path.Join("dataplanes", name+".yaml")] = dp
+			g.addInstance(compose, s, name)
+		}
+	}
+	b, err := yaml.Marshal(compose)
+	if err != nil {
+		return nil, err
+	}
+	out["docker-compose.yaml"] = b
+	out["bootstrap.sh"] = []byte(g.bootstrapScript())
+	return out, nil
+}
+
+func (g Generator) serviceName(idx int) string {
+	return fmt.Sprintf("%s-%03d", g.opts.baseName, idx)
+}
+
+func (g Generator) instanceName(idx int, replica int) string {
+	return fmt.Sprintf("%s-%d", g.serviceName(idx), replica)
+}
+
+func (g Generator) dataplane(svc apis.Service, name string) Dataplane {
+	dp := Dataplane{
+		Type: "Dataplane",
+		Mesh: g.opts.mesh,
+		Name: name,
+		Networking: Networking{
+			Address: name,
+			Inbound: []Inbound{
+				{
+					Port:           inboundPort,
+					ServicePort:    g.opts.port,
+					ServiceAddress: "127.0.0.1",
+					Tags: map[string]string{
+						"kuma.io/service":  g.serviceName(svc.Idx),
+						"kuma.io/protocol": "http",
+					},
+				},
+			},
+		},
+	}
+	for _, edge := range svc.Edges {
+		dp.Networking.Outbound = append(dp.Networking.Outbound, Outbound{
+			Port: outboundPortBase + edge,
+			Tags: map[string]string{
+				"kuma.io/service": g.serviceName(edge),
+			},
+		})
+	}
+	return dp
+}
+
+func (g Generator) kumaImage(component string) string {
+	return fmt.Sprintf("%s/%s:%s", g.opts.kumaImageRegistry, component, g.opts.kumaVersion)
+}
+
+func (g Generator) controlPlane() Compose {
+	storeEnv := map[string]string{
+		"KUMA_STORE_TYPE":              "postgres",
+		"KUMA_STORE_POSTGRES_HOST":     "postgres",
+		"KUMA_STORE_POSTGRES_PORT":     "5432",
+		"KUMA_STORE_POSTGRES_USER":     "kuma",
+		"KUMA_STORE_POSTGRES_PASSWORD": "kuma",
+		"KUMA_STORE_POSTGRES_DB_NAME":  "kuma",
+	}
+	return Compose{
+		Services: map[string]ComposeService{
+			"postgres": {
+				Image: "postgres:16",
+				Environment: map[string]string{
+					"POSTGRES_USER":     "kuma",
+					"POSTGRES_PASSWORD": "kuma",
+					"POSTGRES_DB":       "kuma",
+				},
+				Healthcheck: &ComposeHealthcheck{
+					Test:     []string{"CMD", "pg_isready", "-U", "kuma"},
+					Interval: "2s",
+					Retries:  30,
+				},
+			},
+			"kuma-migrate": {
+				Image:       g.kumaImage("kuma-cp"),
+				Command:     []string{"migrate", "up"},
+				Environment: storeEnv,
+				DependsOn: map[string]ComposeDependency{
+					"postgres": {Condition: "service_healthy"},
+				},
+			},
+			"kuma-cp": {
+				Image:       g.kumaImage("kuma-cp"),
+				Command:     []string{"run"},
+				Environment: storeEnv,
+				Ports:       []string{"5681:5681"},
+				DependsOn: map[string]ComposeDependency{
+					"kuma-migrate": {Condition: "service_completed_successfully"},
+				},
+			},
+			// kumactl shares the network namespace of the control plane so its API calls come from localhost,
+			// which is an admin on Universal, that's how dataplane tokens are generated without credentials.
+			"kumactl": {
+				Image:       g.kumaImage("kumactl"),
+				NetworkMode: "service:kuma-cp",
+				Profiles:    []string{"tools"},
+				DependsOn: map[string]ComposeDependency{
+					"kuma-cp": {Condition: "service_started"},
+				},
+			},
+		},
+	}
+}
+
+func (g Generator) addInstance(compose Compose, svc apis.Service, name string) {
+	var uris []string
+	for _, edge := range svc.Edges {
+		uris = append(uris, fmt.Sprintf("http://127.0.0.1:%d", outboundPortBase+edge))
+	}
+	compose.Services[name] = ComposeService{
+		Image:    g.opts.image,
+		Hostname: name,
+		Environment: map[string]string{
+			"SERVICE":       g.serviceName(svc.Idx),
+			"LISTEN_ADDR":   fmt.Sprintf("127.0.0.1:%d", g.opts.port),
+			"UPSTREAM_URIS": strings.Join(uris, ","),
+		},
+	}
+	compose.Services[name+"-dp"] = ComposeService{
+		Image:       g.kumaImage("kuma-dp"),
+		NetworkMode: "service:" + name,
+		Command: []string{
+			"run",
+			"--cp-address=https://kuma-cp:5678",
+			fmt.Sprintf("--dataplane-file=/dataplanes/%s.yaml", name),
+			fmt.Sprintf("--dataplane-token-file=/tokens/%s", name),
+		},
+		Volumes: []string{
+			"./dataplanes:/dataplanes:ro",
+			"./tokens:/tokens:ro",
+		},
+		DependsOn: map[string]ComposeDependency{
+			name:      {Condition: "service_started"},
+			"kuma-cp": {Condition: "service_started"},
+		},
+	}
+}
+
+func (g Generator) bootstrapScript() string {
+	return fmt.Sprintf(`#!/usr/bin/env bash
+# Starts the control plane, generates a token for every dataplane in ./dataplanes
+# and then starts all the workloads with their kuma-dp.
+set -euo pipefail
+cd "$(dirname "$0")"
+
+docker compose up --detach kuma-cp
+until docker compose run --rm kumactl get meshes >/dev/null 2>&1; do
+  sleep 1
+done
+
+mkdir -p tokens
+for file in dataplanes/*.yaml; do
+  name="$(basename "$file" .yaml)"
+  docker compose run --rm kumactl generate dataplane-token \
+    --mesh %s --name "$name" --valid-for %s > "tokens/$name"
+done
+
+docker compose up --detach
+`, g.opts.mesh, g.opts.tokenValidity)
+}
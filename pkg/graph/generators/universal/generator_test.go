@@ -0,0 +1,92 @@
+package universal_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/files"
+	"github.com/kong/mesh-perf/pkg/graph/generators/universal"
+)
+
+// Set UPDATE_GOLDEN_FILES=true to regenerate testdata.
+func TestGolden(t *testing.T) {
+	type testCase struct {
+		desc  string
+		dir   string
+		opts  []universal.OptionFn
+		given apis.ServiceGraph
+	}
+	tests := []testCase{
+		{
+			desc: "Simple graph",
+			dir:  "simple",
+			given: apis.ServiceGraph{
+				Services: []apis.Service{
+					{Idx: 0, Edges: []int{1, 2}, Replicas: 2},
+					{Idx: 1, Edges: []int{2}, Replicas: 1},
+					{Idx: 2, Edges: []int{}, Replicas: 1},
+				},
+			},
+		},
+		{
+			desc: "Custom mesh and images",
+			dir:  "custom",
+			opts: []universal.OptionFn{
+				universal.WithMesh("perf"),
+				universal.WithKumaImageRegistry("registry.local"),
+				universal.WithKumaVersion("2.12.0"),
+				universal.WithImage("registry.local/fake-service:v0.26.0"),
+			},
+			given: apis.ServiceGraph{
+				Services: []apis.Service{
+					{Idx: 0, Edges: []int{1}, Replicas: 1},
+					{Idx: 1, Edges: []int{}, Replicas: 1},
+				},
+			},
+		},
+	}
+	for _, tc := range tests {
+		got, err := universal.NewGenerator(tc.opts...).Files(tc.given)
+		if err != nil {
+			t.Fatalf("test: %s, failed generating: %v", tc.desc, err)
+		}
+		dir := filepath.Join("testdata", tc.dir)
+		if os.Getenv("UPDATE_GOLDEN_FILES") == "true" {
+			if err := os.RemoveAll(dir); err != nil {
+				t.Fatal(err)
+			}
+			if err := got.Write(dir); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expected := files.Files{}
+		if err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			content, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			expected[filepath.ToSlash(rel)] = content
+			return nil
+		}); err != nil {
+			t.Fatalf("test: %s, failed reading golden files: %v", tc.desc, err)
+		}
+		if !reflect.DeepEqual(expected.Names(), got.Names()) {
+			t.Fatalf("test: %s, expected files: %v, got: %v", tc.desc, expected.Names(), got.Names())
+		}
+		for _, name := range got.Names() {
+			if string(expected[name]) != string(got[name]) {
+				t.Errorf("test: %s, file %s differs, expected:\n%s\ngot:\n%s", tc.desc, name, expected[name], got[name])
+			}
+		}
+	}
+}
@@ -0,0 +1,19 @@
+#!/usr/bin/env bash
+# Starts the control plane, generates a token for every dataplane in ./dataplanes
+# and then starts all the workloads with their kuma-dp.
+set -euo pipefail
+cd "$(dirname "$0")"
+
+docker compose up --detach kuma-cp
+until docker compose run --rm kumactl get meshes >/dev/null 2>&1; do
+  sleep 1
+done
+
+mkdir -p tokens
+for file in dataplanes/*.yaml; do
+  name="$(basename "$file" .yaml)"
+  docker compose run --rm kumactl generate dataplane-token \
+    --mesh perf --name "$name" --valid-for 24h > "tokens/$name"
+done
+
+docker compose up --detach
@@ -0,0 +1,55 @@
+package universal
+
+// Dataplane is a Universal mode Dataplane resource.
+type Dataplane struct {
+	Type       string     `json:"type"`
+	Mesh       string     `json:"mesh"`
+	Name       string     `json:"name"`
+	Networking Networking `json:"networking"`
+}
+
+type Networking struct {
+	Address  string     `json:"address"`
+	Inbound  []Inbound  `json:"inbound"`
+	Outbound []Outbound `json:"outbound,omitempty"`
+}
+
+type Inbound struct {
+	Port           int               `json:"port"`
+	ServicePort    int               `json:"servicePort"`
+	ServiceAddress string            `json:"serviceAddress,omitempty"`
+	Tags           map[string]string `json:"tags"`
+}
+
+type Outbound struct {
+	Port int               `json:"port"`
+	Tags map[string]string `json:"tags"`
+}
+
+// Compose is the subset of the compose specification we generate.
+type Compose struct {
+	Services map[string]ComposeService `json:"services"`
+}
+
+type ComposeService struct {
+	Image       string                       `json:"image"`
+	Hostname    string                       `json:"hostname,omitempty"`
+	Command     []string                     `json:"command,omitempty"`
+	Environment map[string]string            `json:"environment,omitempty"`
+	Ports       []string                     `json:"ports,omitempty"`
+	Volumes     []string                     `json:"volumes,omitempty"`
+	NetworkMode string                       `json:"network_mode,omitempty"`
+	Profiles    []string                     `json:"profiles,omitempty"`
+	DependsOn   map[string]ComposeDependency `json:"depends_on,omitempty"`
+	Healthcheck *ComposeHealthcheck          `json:"healthcheck,omitempty"`
+}
+
+type ComposeDependency struct {
+	Condition string `json:"condition"`
+}
+
+type ComposeHealthcheck struct {
+	Test     []string `json:"test"`
+	Interval string   `json:"interval,omitempty"`
+	Retries  int      `json:"retries,omitempty"`
+}
@@ -0,0 +1,207 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// The environment variables of the config.
+const (
+	TargetsEnv    = "TARGETS"
+	RPSEnv        = "RPS"
+	TimeoutEnv    = "REQUEST_TIMEOUT"
+	ListenAddrEnv = "LISTEN_ADDR"
+)
+
+const (
+	DefaultListenAddr = "0.0.0.0:9090"
+	DefaultTimeout    = 10 * time.Second
+)
+
+type Config struct {
+	// Targets are the URLs called, each one at RPS.
+	Targets    []string
+	RPS        float64
+	Timeout    time.Duration
+	ListenAddr string
+}
+
+// ConfigFromEnv reads the config from the environment, lookupEnv is usually os.LookupEnv.
+func ConfigFromEnv(lookupEnv func(string) (string, bool)) (Config, error) {
+	config := Config{
+		Timeout:    DefaultTimeout,
+		ListenAddr: DefaultListenAddr,
+	}
+	if v, ok := lookupEnv(TargetsEnv); ok {
+		for _, target := range strings.Split(v, ",") {
+			if target = strings.TrimSpace(target); target != "" {
+				config.Targets = append(config.Targets, target)
+			}
+		}
+	}
+	v, _ := lookupEnv(RPSEnv)
+	rps, err := strconv.ParseFloat(v, 64)
+	if err != nil || rps <= 0 {
+		return Config{}, fmt.Errorf("invalid %s %q, must be a positive number", RPSEnv, v)
+	}
+	config.RPS = rps
+	if v, ok := lookupEnv(TimeoutEnv); ok && v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid %s: %w", TimeoutEnv, err)
+		}
+		config.Timeout = timeout
+	}
+	if v, ok := lookupEnv(ListenAddrEnv); ok && v != "" {
+		config.ListenAddr = v
+	}
+	return config, nil
+}
+
+// LoadGenerator calls every target at a constant rate, whatever the latency of the responses, and exports
+// the latency and status codes of the requests on /metrics.
+type LoadGenerator struct {
+	config Config
+	client *http.Client
+
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+type Option func(*LoadGenerator)
+
+// WithClient sets the client calling the targets.
+func WithClient(client *http.Client) Option {
+	return func(l *LoadGenerator) {
+		l.client = client
+	}
+}
+
+func New(config Config, opts ...Option) *LoadGenerator {
+	l := &LoadGenerator{
+		config:   config,
+		client:   &http.Client{},
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "loadgen_requests_total",
+			Help: "Requests sent, by target and status code, code is 0 when the request failed.",
+		}, []string{"target", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "loadgen_request_duration_seconds",
+			Help:    "Duration of the requests, by target and status code, code is 0 when the request failed.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"target", "code"}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.registry.MustRegister(l.requests, l.duration)
+	return l
+}
+
+func (l *LoadGenerator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("GET /metrics", promhttp.HandlerFor(l.registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// Run calls the targets until the context is done and their last requests are over.
+func (l *LoadGenerator) Run(ctx context.Context) {
+	interval := time.Duration(float64(time.Second) / l.config.RPS)
+	wg := sync.WaitGroup{}
+	for _, target := range l.config.Targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				// requests don't wait for the previous ones, so slow responses don't lower the rate
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					l.call(ctx, target)
+				}()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (l *LoadGenerator) call(ctx context.Context, target string) {
+	ctx, cancel := context.WithTimeout(ctx, l.config.Timeout)
+	defer cancel()
+	start := time.Now()
+	code := 0
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = l.client.Do(req); err == nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			code = resp.StatusCode
+		}
+	}
+	// requests cut by the end of the run aren't failures
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	l.requests.WithLabelValues(target, strconv.Itoa(code)).Inc()
+	l.duration.WithLabelValues(target, strconv.Itoa(code)).Observe(time.Since(start).Seconds())
+}
+
+// ListenAndServe runs the load and serves the metrics until the context is done.
+func (l *LoadGenerator) ListenAndServe(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              l.config.ListenAddr,
+		Handler:           l.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		l.Run(runCtx)
+		close(done)
+	}()
+	select {
+	case err := <-errCh:
+		cancel()
+		<-done
+		return err
+	case <-ctx.Done():
+	}
+	<-done
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package loadgen_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kong/mesh-perf/pkg/loadgen"
+)
+
+func TestLoadGenerator(t *testing.T) {
+	var calls atomic.Int64
+	ok := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	l := loadgen.New(loadgen.Config{Targets: []string{ok.URL, failing.URL}, RPS: 100, Timeout: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	l.Run(ctx)
+
+	// about 50 calls, with room for slow machines
+	if got := calls.Load(); got < 20 || got > 60 {
+		t.Errorf("expected about 50 calls at 100 RPS in 500ms, got: %d", got)
+	}
+
+	metrics := httptest.NewServer(l.Handler())
+	defer metrics.Close()
+	resp, err := http.Get(metrics.URL + "/metrics")
+	if err != nil {
+		t.Fatal("failed getting the metrics", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal("failed reading the metrics", err)
+	}
+	for _, s := range []string{
+		`loadgen_requests_total{code="200",target="` + ok.URL + `"}`,
+		`loadgen_requests_total{code="503",target="` + failing.URL + `"}`,
+		`loadgen_request_duration_seconds_bucket{code="200",target="` + ok.URL + `"`,
+	} {
+		if !strings.Contains(string(body), s) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", s, body)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	env := map[string]string{"TARGETS": "http://a:9090,http://b:9090", "RPS": "2.5"}
+	lookupEnv := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	config, err := loadgen.ConfigFromEnv(lookupEnv)
+	if err != nil {
+		t.Fatal("failed reading the config", err)
+	}
+	if len(config.Targets) != 2 || config.RPS != 2.5 || config.Timeout != loadgen.DefaultTimeout {
+		t.Errorf("unexpected config: %+v", config)
+	}
+	delete(env, "RPS")
+	if _, err := loadgen.ConfigFromEnv(lookupEnv); err == nil {
+		t.Error("expected a missing RPS to fail")
+	}
+}
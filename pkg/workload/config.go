@@ -0,0 +1,151 @@
+package workload
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// The environment variables of the config, the same ones fake-service reads.
+const (
+	NameEnv            = "NAME"
+	ServiceEnv         = "SERVICE"
+	ListenAddrEnv      = "LISTEN_ADDR"
+	UpstreamURIsEnv    = "UPSTREAM_URIS"
+	Timing50Env        = "TIMING_50_PERCENTILE"
+	Timing90Env        = "TIMING_90_PERCENTILE"
+	Timing99Env        = "TIMING_99_PERCENTILE"
+	ErrorRateEnv       = "ERROR_RATE"
+	ErrorCodeEnv       = "ERROR_CODE"
+	UpstreamTimeoutEnv = "HTTP_CLIENT_REQUEST_TIMEOUT"
+	UpstreamWorkersEnv = "UPSTREAM_WORKERS"
+	TLSCertEnv         = "TLS_CERT_LOCATION"
+	TLSKeyEnv          = "TLS_KEY_LOCATION"
+	// TLSCAEnv is only read by the workload, fake-service doesn't verify https upstreams with a CA.
+	TLSCAEnv = "TLS_CA_LOCATION"
+	// ConfigFileEnv is the path of a YAML file of the variables above, they override the environment and
+	// the upstreams, timings and errors are reloaded when it changes.
+	ConfigFileEnv = "CONFIG_FILE"
+)
+
+const (
+	DefaultListenAddr      = "0.0.0.0:9090"
+	DefaultErrorCode       = 500
+	DefaultUpstreamTimeout = 30 * time.Second
+)
+
+type Config struct {
+	Name       string
+	ListenAddr string
+	// Upstreams are the URLs called in parallel on every request.
+	Upstreams []string
+	// Timing50, Timing90 and Timing99 are the latencies added to the 50th, 90th and 99th percentile of requests.
+	Timing50 time.Duration
+	Timing90 time.Duration
+	Timing99 time.Duration
+	// ErrorRate is the ratio of requests, between 0 and 1, failing with ErrorCode.
+	ErrorRate       float64
+	ErrorCode       int
+	UpstreamTimeout time.Duration
+	// UpstreamWorkers is the number of upstreams called concurrently, all of them when 0.
+	UpstreamWorkers int
+	// TLSCertFile and TLSKeyFile make the workload serve HTTPS.
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSCAFile is the CA the certificates of https upstreams are verified with, the system roots when empty.
+	TLSCAFile string
+}
+
+// ConfigFromEnv reads the config from the environment, lookupEnv is usually os.LookupEnv.
+func ConfigFromEnv(lookupEnv func(string) (string, bool)) (Config, error) {
+	config := Config{
+		ListenAddr:      DefaultListenAddr,
+		ErrorCode:       DefaultErrorCode,
+		UpstreamTimeout: DefaultUpstreamTimeout,
+	}
+	if v, ok := lookupEnv(ServiceEnv); ok {
+		config.Name = v
+	}
+	if v, ok := lookupEnv(NameEnv); ok {
+		config.Name = v
+	}
+	if v, ok := lookupEnv(ListenAddrEnv); ok && v != "" {
+		config.ListenAddr = v
+	}
+	if v, ok := lookupEnv(UpstreamURIsEnv); ok {
+		for _, uri := range strings.Split(v, ",") {
+			if uri = strings.TrimSpace(uri); uri != "" {
+				config.Upstreams = append(config.Upstreams, uri)
+			}
+		}
+	}
+	durations := map[string]*time.Duration{
+		Timing50Env:        &config.Timing50,
+		Timing90Env:        &config.Timing90,
+		Timing99Env:        &config.Timing99,
+		UpstreamTimeoutEnv: &config.UpstreamTimeout,
+	}
+	for env, d := range durations {
+		v, ok := lookupEnv(env)
+		if !ok || v == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid %s: %w", env, err)
+		}
+		*d = parsed
+	}
+	if v, ok := lookupEnv(ErrorRateEnv); ok && v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return Config{}, fmt.Errorf("invalid %s %q, must be between 0 and 1", ErrorRateEnv, v)
+		}
+		config.ErrorRate = rate
+	}
+	if v, ok := lookupEnv(ErrorCodeEnv); ok && v != "" {
+		code, err := strconv.Atoi(v)
+		if err != nil || code < 100 || code > 599 {
+			return Config{}, fmt.Errorf("invalid %s %q, must be an HTTP status code", ErrorCodeEnv, v)
+		}
+		config.ErrorCode = code
+	}
+	if v, ok := lookupEnv(UpstreamWorkersEnv); ok && v != "" {
+		workers, err := strconv.Atoi(v)
+		if err != nil || workers < 0 {
+			return Config{}, fmt.Errorf("invalid %s %q, must be a positive number", UpstreamWorkersEnv, v)
+		}
+		config.UpstreamWorkers = workers
+	}
+	if v, ok := lookupEnv(TLSCertEnv); ok {
+		config.TLSCertFile = v
+	}
+	if v, ok := lookupEnv(TLSKeyEnv); ok {
+		config.TLSKeyFile = v
+	}
+	if v, ok := lookupEnv(TLSCAEnv); ok {
+		config.TLSCAFile = v
+	}
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		return Config{}, fmt.Errorf("%s and %s must be set together", TLSCertEnv, TLSKeyEnv)
+	}
+	return config, nil
+}
+
+// ConfigFromFile reads the config from the content of a config file, with the environment for the variables
+// it doesn't set.
+func ConfigFromFile(data []byte, lookupEnv func(string) (string, bool)) (Config, error) {
+	vars := map[string]string{}
+	if err := yaml.UnmarshalStrict(data, &vars); err != nil {
+		return Config{}, fmt.Errorf("invalid config file: %w", err)
+	}
+	return ConfigFromEnv(func(name string) (string, bool) {
+		if v, ok := vars[name]; ok {
+			return v, true
+		}
+		return lookupEnv(name)
+	})
+}
@@ -0,0 +1,287 @@
+package workload
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Response is the body of the responses, with the responses of the upstreams nested.
+type Response struct {
+	Name          string     `json:"name"`
+	Code          int        `json:"code"`
+	Duration      string     `json:"duration"`
+	Error         string     `json:"error,omitempty"`
+	UpstreamCalls []Response `json:"upstream_calls,omitempty"`
+}
+
+// Server is the perf workload, it answers requests after calling its upstreams and injecting latency
+// and errors. Metrics are on /metrics and health checks on /health and /ready.
+type Server struct {
+	config atomic.Pointer[Config]
+	client *http.Client
+	random func() float64
+
+	registry         *prometheus.Registry
+	requests         *prometheus.CounterVec
+	upstreamDuration *prometheus.HistogramVec
+}
+
+type Option func(*Server)
+
+// WithClient sets the client calling the upstreams.
+func WithClient(client *http.Client) Option {
+	return func(s *Server) {
+		s.client = client
+	}
+}
+
+// NewClient returns a client calling the upstreams of the config, it verifies https upstreams with the CA of
+// the config. The client is kept on reloads.
+func NewClient(config Config) (*http.Client, error) {
+	if config.TLSCAFile == "" {
+		return &http.Client{}, nil
+	}
+	ca, err := os.ReadFile(config.TLSCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate in %s", config.TLSCAFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return &http.Client{Transport: transport}, nil
+}
+
+// WithRandom sets the source of the numbers in [0, 1) picking the latency and errors of requests.
+func WithRandom(random func() float64) Option {
+	return func(s *Server) {
+		s.random = random
+	}
+}
+
+func New(config Config, opts ...Option) *Server {
+	s := &Server{
+		client:   &http.Client{},
+		random:   rand.Float64,
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "workload_requests_total",
+			Help: "Requests served, by status code.",
+		}, []string{"code"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "workload_upstream_request_duration_seconds",
+			Help:    "Duration of the calls to upstreams, by upstream and status code, code is 0 when the call failed.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"upstream", "code"}),
+	}
+	s.config.Store(&config)
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.registry.MustRegister(s.requests, s.upstreamDuration, collectors.NewGoCollector())
+	return s
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", healthy)
+	mux.HandleFunc("GET /ready", healthy)
+	mux.Handle("GET /metrics", promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/", s.serve)
+	return mux
+}
+
+// Reload replaces the upstreams, timings and errors of the config, the listener keeps its address and TLS.
+func (s *Server) Reload(config Config) {
+	s.config.Store(&config)
+}
+
+// WatchConfigFile reloads the config file at the path every interval until the context is done. An invalid
+// config file is logged and the previous config is kept.
+func (s *Server) WatchConfigFile(ctx context.Context, path string, lookupEnv func(string) (string, bool), interval time.Duration) {
+	var last []byte
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			slog.Error("failed reading the config file", "path", path, "error", err)
+			continue
+		}
+		if last != nil && bytes.Equal(data, last) {
+			continue
+		}
+		last = data
+		config, err := ConfigFromFile(data, lookupEnv)
+		if err != nil {
+			slog.Error("invalid config file", "path", path, "error", err)
+			continue
+		}
+		s.Reload(config)
+	}
+}
+
+// ListenAndServe serves on the listen address until the context is done, then shuts down gracefully.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	config := s.config.Load()
+	server := &http.Server{
+		Addr:              config.ListenAddr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		if config.TLSCertFile != "" {
+			errCh <- server.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+			return
+		}
+		errCh <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func healthy(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	config := s.config.Load()
+	latency := s.latency(config)
+	response := Response{
+		Name:          config.Name,
+		Code:          http.StatusOK,
+		UpstreamCalls: s.callUpstreams(r.Context(), config),
+	}
+	for _, call := range response.UpstreamCalls {
+		if call.Code != http.StatusOK {
+			response.Code = http.StatusInternalServerError
+			response.Error = "upstream call failed"
+		}
+	}
+	if response.Code == http.StatusOK && s.random() < config.ErrorRate {
+		response.Code = config.ErrorCode
+		response.Error = "injected error"
+	}
+	select {
+	case <-time.After(latency - time.Since(start)):
+	case <-r.Context().Done():
+	}
+	response.Duration = time.Since(start).String()
+
+	s.requests.WithLabelValues(strconv.Itoa(response.Code)).Inc()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.Code)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// latency picks the latency of a request from the percentiles of the config.
+func (s *Server) latency(config *Config) time.Duration {
+	switch p := s.random(); {
+	case p < 0.5:
+		return config.Timing50
+	case p < 0.9:
+		return config.Timing90
+	default:
+		return config.Timing99
+	}
+}
+
+func (s *Server) callUpstreams(ctx context.Context, config *Config) []Response {
+	responses := make([]Response, len(config.Upstreams))
+	workers := config.UpstreamWorkers
+	if workers == 0 {
+		workers = len(config.Upstreams)
+	}
+	sem := make(chan struct{}, max(workers, 1))
+	wg := sync.WaitGroup{}
+	for i, upstream := range config.Upstreams {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			responses[i] = s.callUpstream(ctx, upstream, config.UpstreamTimeout)
+		}()
+	}
+	wg.Wait()
+	return responses
+}
+
+func (s *Server) callUpstream(ctx context.Context, upstream string, timeout time.Duration) Response {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	response, err := s.get(ctx, upstream)
+	duration := time.Since(start)
+	if err != nil {
+		response = Response{Name: upstream, Error: err.Error()}
+	}
+	s.upstreamDuration.WithLabelValues(upstream, strconv.Itoa(response.Code)).Observe(duration.Seconds())
+	response.Duration = duration.String()
+	return response
+}
+
+func (s *Server) get(ctx context.Context, upstream string) (Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, upstream, nil)
+	if err != nil {
+		return Response{}, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, err
+	}
+	response := Response{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		// not a workload, keep the status code of the response
+		response = Response{Name: upstream}
+	}
+	response.Code = resp.StatusCode
+	return response, nil
+}
@@ -0,0 +1,199 @@
+package workload_test
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kong/mesh-perf/pkg/workload"
+)
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal("failed calling", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal("failed reading the body", err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestServer(t *testing.T) {
+	leaf := httptest.NewServer(workload.New(workload.Config{
+		Name: "leaf", Timing50: 10 * time.Millisecond, Timing90: 10 * time.Millisecond, Timing99: 10 * time.Millisecond,
+	}).Handler())
+	defer leaf.Close()
+	failing := httptest.NewServer(workload.New(workload.Config{Name: "failing", ErrorRate: 1, ErrorCode: 503}).Handler())
+	defer failing.Close()
+	root := httptest.NewServer(workload.New(workload.Config{Name: "root", Upstreams: []string{leaf.URL}}).Handler())
+	defer root.Close()
+	broken := httptest.NewServer(workload.New(workload.Config{Name: "broken", Upstreams: []string{leaf.URL, failing.URL}}).Handler())
+	defer broken.Close()
+
+	for _, path := range []string{"/health", "/ready"} {
+		if code, _ := get(t, root.URL+path); code != http.StatusOK {
+			t.Errorf("expected %s to return 200, got: %d", path, code)
+		}
+	}
+
+	code, body := get(t, root.URL)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got: %d %s", code, body)
+	}
+	response := workload.Response{}
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal("failed parsing the response", err)
+	}
+	if response.Name != "root" || len(response.UpstreamCalls) != 1 || response.UpstreamCalls[0].Name != "leaf" {
+		t.Errorf("expected root to call leaf, got: %s", body)
+	}
+	if d, err := time.ParseDuration(response.UpstreamCalls[0].Duration); err != nil || d < 10*time.Millisecond {
+		t.Errorf("expected leaf to take at least 10ms, got: %s", response.UpstreamCalls[0].Duration)
+	}
+
+	if code, body := get(t, failing.URL); code != 503 || !strings.Contains(body, "injected error") {
+		t.Errorf("expected an injected 503, got: %d %s", code, body)
+	}
+	if code, body := get(t, broken.URL); code != http.StatusInternalServerError || !strings.Contains(body, `"code":503`) {
+		t.Errorf("expected a 500 from the failing upstream, got: %d %s", code, body)
+	}
+
+	_, metrics := get(t, root.URL+"/metrics")
+	for _, s := range []string{
+		`workload_requests_total{code="200"} 1`,
+		`workload_upstream_request_duration_seconds_count{code="200",upstream="` + leaf.URL + `"} 1`,
+	} {
+		if !strings.Contains(metrics, s) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", s, metrics)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	env := map[string]string{
+		"SERVICE":                     "fake-service-000",
+		"UPSTREAM_URIS":               "http://fake-service-001:9090, http://fake-service-002:9090",
+		"TIMING_99_PERCENTILE":        "1s",
+		"ERROR_RATE":                  "0.1",
+		"HTTP_CLIENT_REQUEST_TIMEOUT": "5s",
+		"UPSTREAM_WORKERS":            "1",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	config, err := workload.ConfigFromEnv(lookupEnv)
+	if err != nil {
+		t.Fatal("failed reading the config", err)
+	}
+	if config.Name != "fake-service-000" || config.ListenAddr != workload.DefaultListenAddr ||
+		len(config.Upstreams) != 2 || config.Upstreams[1] != "http://fake-service-002:9090" ||
+		config.Timing99 != time.Second || config.ErrorRate != 0.1 || config.ErrorCode != workload.DefaultErrorCode ||
+		config.UpstreamTimeout != 5*time.Second || config.UpstreamWorkers != 1 {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	env["ERROR_RATE"] = "2"
+	if _, err := workload.ConfigFromEnv(lookupEnv); err == nil {
+		t.Error("expected an error rate over 1 to fail")
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal("failed writing the config file", err)
+		}
+	}
+	write("SERVICE: foo\nERROR_RATE: \"1\"\nERROR_CODE: \"503\"\n")
+	lookupEnv := func(name string) (string, bool) {
+		if name == "ERROR_CODE" {
+			return "502", true
+		}
+		return "", false
+	}
+	config, err := workload.ConfigFromFile([]byte("SERVICE: foo\n"), lookupEnv)
+	if err != nil {
+		t.Fatal("failed reading the config", err)
+	}
+	if config.Name != "foo" || config.ErrorCode != 502 {
+		t.Errorf("expected the name of the file and the error code of the environment, got: %+v", config)
+	}
+
+	server := workload.New(config)
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+	if code, _ := get(t, ts.URL); code != http.StatusOK {
+		t.Fatalf("expected 200 before the reload, got: %d", code)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.WatchConfigFile(ctx, path, lookupEnv, 10*time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		code, _ := get(t, ts.URL)
+		if code == http.StatusServiceUnavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 503 after the reload, got: %d", code)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := workload.ConfigFromFile([]byte("FOO: bar\nSERVICE: [foo]\n"), lookupEnv); err == nil {
+		t.Error("expected an invalid config file to fail")
+	}
+}
+
+func TestTLSUpstream(t *testing.T) {
+	leaf := httptest.NewTLSServer(workload.New(workload.Config{Name: "leaf"}).Handler())
+	defer leaf.Close()
+	ca := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal("failed writing the CA", err)
+	}
+
+	for desc, tc := range map[string]struct {
+		caFile string
+		code   int
+	}{
+		"verified with the CA": {caFile: ca, code: http.StatusOK},
+		"unknown authority":    {code: 0},
+	} {
+		config := workload.Config{Name: "root", Upstreams: []string{leaf.URL}, TLSCAFile: tc.caFile}
+		client, err := workload.NewClient(config)
+		if err != nil {
+			t.Fatalf("test: %s, failed creating the client: %v", desc, err)
+		}
+		root := httptest.NewServer(workload.New(config, workload.WithClient(client)).Handler())
+		_, body := get(t, root.URL)
+		root.Close()
+		response := workload.Response{}
+		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			t.Fatalf("test: %s, failed parsing the response: %v", desc, err)
+		}
+		if len(response.UpstreamCalls) != 1 || response.UpstreamCalls[0].Code != tc.code {
+			t.Errorf("test: %s, expected the upstream to return %d, got: %s", desc, tc.code, body)
+		}
+	}
+
+	if _, err := workload.NewClient(workload.Config{TLSCAFile: filepath.Join(t.TempDir(), "missing.crt")}); err == nil {
+		t.Error("expected a missing CA to fail")
+	}
+}
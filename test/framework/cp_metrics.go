@@ -3,6 +3,7 @@ package framework
 import (
 	"context"
 	"errors"
+	"fmt"
 	"syscall"
 	"time"
 )
@@ -15,6 +16,22 @@ func XdsAckRequestsReceived(ctx context.Context, promClient *PromClient) (int, e
 	return promClient.QueryIntValue(ctx, `sum(xds_requests_received{confirmation="ACK"})`)
 }
 
+// XdsGenerationSum is the total time in milliseconds the control plane spent generating xDS config.
+func XdsGenerationSum(ctx context.Context, promClient *PromClient) (float64, error) {
+	return promClient.QueryFloatValue(ctx, "sum(xds_generation_sum)")
+}
+
+func XdsGenerationCount(ctx context.Context, promClient *PromClient) (int, error) {
+	return promClient.QueryIntValue(ctx, "sum(xds_generation_count)")
+}
+
+// CpMemoryWorkingSetBytes is the memory used by the control plane containers in the namespace.
+func CpMemoryWorkingSetBytes(ctx context.Context, promClient *PromClient, namespace string) (int, error) {
+	return promClient.QueryIntValue(ctx, fmt.Sprintf(
+		`sum(container_memory_working_set_bytes{namespace=%q,container="control-plane"})`, namespace,
+	))
+}
+
 func WatchXdsDeliveryCount(
 	ctx context.Context,
 	promClient *PromClient,
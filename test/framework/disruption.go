@@ -0,0 +1,163 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+)
+
+const (
+	loadGeneratorErrorsQuery    = `sum(loadgen_requests_total{code!~"2.."})`
+	loadGeneratorSuccessesQuery = `sum(loadgen_requests_total{code=~"2.."})`
+	// loadGeneratorP99Query is the p99 latency of the requests of the load generator over a window, in seconds.
+	loadGeneratorP99Query = `histogram_quantile(0.99, sum by (le) (increase(loadgen_request_duration_seconds_bucket[%ds])))`
+	// disruptionBaselineWindow is the window before the sampling the p99 latency is compared to.
+	disruptionBaselineWindow = time.Minute
+)
+
+// Disruption is what the requests of the load generator went through during a window.
+type Disruption struct {
+	// Errors are the failed requests, with a status code other than 2xx or no response.
+	Errors int
+	// MaxErrorBurst is the most failed requests in a row of samples with failures.
+	MaxErrorBurst int
+	// P99Before and P99During are the p99 latencies before and during the window.
+	P99Before time.Duration
+	P99During time.Duration
+}
+
+// P99Change is how much the p99 latency grew during the window.
+func (d Disruption) P99Change() time.Duration {
+	return d.P99During - d.P99Before
+}
+
+// AddReportEntries attaches the disruption to the report of the spec.
+func (d Disruption) AddReportEntries() {
+	ginkgo.AddReportEntry("disruption_errors", d.Errors)
+	ginkgo.AddReportEntry("disruption_max_error_burst", d.MaxErrorBurst)
+	ginkgo.AddReportEntry("disruption_p99_latency_change", d.P99Change().Milliseconds())
+}
+
+// DisruptionSampler samples the failed requests of the load generator of graph_k8s.WithLoadGenerator from
+// Prometheus until it's stopped, so failures during changes of the mesh are measured. Samples are only as
+// fine as the scrape interval of Prometheus.
+type DisruptionSampler struct {
+	promClient *PromClient
+	start      time.Time
+	stopCh     chan struct{}
+	done       chan struct{}
+
+	mu         sync.Mutex
+	disruption Disruption
+	err        error
+}
+
+// StartDisruptionSampler starts sampling every interval. It doesn't take the context of a spec, it samples
+// until Stop, which can be deferred past the end of the spec with DeferCleanup. It fails when the load generator
+// has no successful requests yet, its errors would then be the ones of a load generator that can't reach the
+// graph, like one denied by the mesh, rather than a disruption.
+func StartDisruptionSampler(promClient *PromClient, interval time.Duration) (*DisruptionSampler, error) {
+	ctx := context.Background()
+	successes, err := LoadGeneratorSuccesses(ctx, promClient)
+	if err != nil {
+		return nil, err
+	}
+	if successes == 0 {
+		return nil, errors.New("the load generator has no 2xx responses to compare a disruption to")
+	}
+	p99Before, err := queryP99(ctx, promClient, disruptionBaselineWindow)
+	if err != nil {
+		return nil, err
+	}
+	baseline, err := queryErrors(ctx, promClient)
+	if err != nil {
+		return nil, err
+	}
+	s := &DisruptionSampler{
+		promClient: promClient,
+		start:      time.Now(),
+		stopCh:     make(chan struct{}),
+		done:       make(chan struct{}),
+		disruption: Disruption{P99Before: p99Before},
+	}
+	go s.sample(baseline, interval)
+	return s, nil
+}
+
+func (s *DisruptionSampler) sample(last int, interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	burst := 0
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+		}
+		errs, err := queryErrors(context.Background(), s.promClient)
+		if err != nil {
+			s.mu.Lock()
+			s.err = errors.Join(s.err, err)
+			s.mu.Unlock()
+			continue
+		}
+		// the counter restarts with the load generator
+		delta := max(errs-last, 0)
+		last = errs
+		if delta == 0 {
+			burst = 0
+			continue
+		}
+		burst += delta
+		s.mu.Lock()
+		s.disruption.Errors += delta
+		s.disruption.MaxErrorBurst = max(s.disruption.MaxErrorBurst, burst)
+		s.mu.Unlock()
+	}
+}
+
+// Stop stops the sampling and returns the disruption during the window, with the errors of the samples.
+func (s *DisruptionSampler) Stop() (Disruption, error) {
+	close(s.stopCh)
+	<-s.done
+	window := time.Duration(math.Ceil(time.Since(s.start).Seconds())) * time.Second
+	p99During, err := queryP99(context.Background(), s.promClient, window)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disruption.P99During = p99During
+	return s.disruption, errors.Join(s.err, err)
+}
+
+// LoadGeneratorSuccesses is the number of requests of the load generator that got a 2xx response.
+func LoadGeneratorSuccesses(ctx context.Context, promClient *PromClient) (int, error) {
+	successes, err := promClient.QueryIntValue(ctx, loadGeneratorSuccessesQuery)
+	if errors.Is(err, ErrNoResults) {
+		return 0, nil
+	}
+	return successes, err
+}
+
+func queryErrors(ctx context.Context, promClient *PromClient) (int, error) {
+	errs, err := promClient.QueryIntValue(ctx, loadGeneratorErrorsQuery)
+	if errors.Is(err, ErrNoResults) {
+		return 0, nil
+	}
+	return errs, err
+}
+
+func queryP99(ctx context.Context, promClient *PromClient, window time.Duration) (time.Duration, error) {
+	p99, err := promClient.QueryFloatValue(ctx, fmt.Sprintf(loadGeneratorP99Query, int(window.Seconds())))
+	if errors.Is(err, ErrNoResults) || math.IsNaN(p99) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(p99 * float64(time.Second)), nil
+}
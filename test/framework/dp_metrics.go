@@ -0,0 +1,21 @@
+package framework
+
+import (
+	"context"
+)
+
+// UpstreamRequestErrors is the number of requests proxies got a 5xx response for from their upstreams.
+func UpstreamRequestErrors(ctx context.Context, promClient *PromClient) (int, error) {
+	return promClient.QueryIntValue(ctx, `sum(envoy_cluster_upstream_rq_xx{envoy_response_code_class="5"})`)
+}
+
+// UpstreamConnectionFailures is the number of connections proxies failed to open to their upstreams.
+func UpstreamConnectionFailures(ctx context.Context, promClient *PromClient) (int, error) {
+	return promClient.QueryIntValue(ctx, `sum(envoy_cluster_upstream_cx_connect_fail)`)
+}
+
+// InboundRejections is the number of connections proxies rejected on their inbounds, like plaintext ones when
+// mTLS is strict, which fail the TLS handshake or match no filter chain.
+func InboundRejections(ctx context.Context, promClient *PromClient) (int, error) {
+	return promClient.QueryIntValue(ctx, `sum({__name__=~"envoy_listener_(ssl_connection_error|no_filter_chain_match)"})`)
+}
@@ -182,6 +182,11 @@ func NewPromClient(cluster *framework.K8sCluster, ns string) (*PromClient, error
 var ErrNoResults = errors.New("no results found for the query")
 
 func (p *PromClient) QueryIntValue(ctx context.Context, query string) (int, error) {
+	val, err := p.QueryFloatValue(ctx, query)
+	return int(val), err
+}
+
+func (p *PromClient) QueryFloatValue(ctx context.Context, query string) (float64, error) {
 	result, _, err := p.queryClient.Query(ctx, query, time.Now())
 	if err != nil {
 		return 0, err
@@ -196,7 +201,7 @@ func (p *PromClient) QueryIntValue(ctx context.Context, query string) (int, erro
 		return 0, ErrNoResults
 	}
 
-	return int(vector[0].Value), nil
+	return float64(vector[0].Value), nil
 }
 
 // GetPrometheusServerEndpoint creates port forward to prometheus-server pod using component=server label
@@ -0,0 +1,86 @@
+package k8s_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/kumahq/kuma/v2/test/framework"
+
+	graph_apis "github.com/kong/mesh-perf/pkg/graph/apis"
+	graph_k8s "github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/test/framework"
+)
+
+func Canary() {
+	var svcGraph graph_apis.ServiceGraph
+	weights := []int{10, 25, 50, 75, 100}
+	graphManifests := map[int]string{}
+
+	versions := func(canaryWeight int) graph_k8s.Option {
+		return graph_k8s.WithVersions(
+			graph_k8s.Version{Name: "v1", Weight: 100 - canaryWeight},
+			graph_k8s.Version{Name: "v2", Weight: canaryWeight},
+		)
+	}
+
+	BeforeAll(func() {
+		installMesh()
+
+		svcGraph = graph_apis.GenerateRandomMesh(
+			872835240,
+			suiteNumServices,
+			50,
+			suiteNumInstances,
+			suiteNumInstances,
+		)
+		for _, weight := range weights {
+			graphManifests[weight] = graphYaml(svcGraph, versions(weight), spreadAcrossNodes())
+		}
+		// every version runs the instances of the service
+		deployGraph(svcGraph, 2*suiteNumServices*suiteNumInstances, versions(0), spreadAcrossNodes())
+	})
+
+	BeforeEach(func() {
+		Expect(framework.PushReportSpecMetric(cluster, obsNamespace, 1)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(framework.PushReportSpecMetric(cluster, obsNamespace, 0)).To(Succeed())
+	})
+
+	E2EAfterAll(func() {
+		Expect(cluster.DeleteNamespace(TestNamespace)).To(Succeed())
+		Expect(cluster.DeleteKuma()).To(Succeed())
+	})
+
+	for _, weight := range weights {
+		It(fmt.Sprintf("should shift %d%% of the traffic to the canary", weight), func(ctx context.Context) {
+			promClient, err := framework.NewPromClient(cluster, obsNamespace)
+			Expect(err).ToNot(HaveOccurred())
+
+			// only the routes change, the workloads are applied unchanged
+			acks := waitForStableAcks(ctx, promClient)
+			Expect(cluster.Install(YamlK8s(graphManifests[weight]))).To(Succeed())
+			propagationStart := time.Now()
+
+			// every proxy with an upstream gets the new weights
+			expectedAcks := 0
+			for _, svc := range svcGraph.Services {
+				if len(svc.Edges) != 0 {
+					expectedAcks += 2 * svc.Replicas
+				}
+			}
+			Eventually(func(g Gomega) {
+				newAcks, err := framework.XdsAckRequestsReceived(ctx, promClient)
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(newAcks - acks).To(BeNumerically(">=", expectedAcks))
+			}, "10m", "5s").Should(Succeed())
+			AddReportEntry("canary_weight", weight)
+			AddReportEntry("route_propagation_duration", time.Since(propagationStart).Milliseconds())
+		})
+	}
+}
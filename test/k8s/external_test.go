@@ -0,0 +1,151 @@
+package k8s_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/kumahq/kuma/v2/test/framework"
+	"github.com/kumahq/kuma/v2/test/framework/envoy_admin"
+	"github.com/kumahq/kuma/v2/test/framework/envoy_admin/tunnel"
+
+	graph_apis "github.com/kong/mesh-perf/pkg/graph/apis"
+	graph_k8s "github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/fakeservice"
+	"github.com/kong/mesh-perf/test/framework"
+)
+
+// External measures the propagation of external services reached through MeshExternalServices, or through a
+// MeshPassthrough in passthrough mode.
+func External(passthrough bool) func() {
+	return func() {
+		external(passthrough)
+	}
+}
+
+func external(passthrough bool) {
+	var svcGraph graph_apis.ServiceGraph
+	var meshPods, externalPods int
+	var deployStart time.Time
+	var admin envoy_admin.Tunnel
+	var caller, externalSvc string
+	var graphManifest string
+
+	BeforeAll(func() {
+		svcGraph = graph_apis.GenerateRandomMesh(
+			872835240,
+			suiteNumServices,
+			50,
+			suiteNumInstances,
+			suiteNumInstances,
+		)
+		// every other leaf is an external service
+		for i, idx := range svcGraph.Leaves() {
+			if i%2 == 0 {
+				svcGraph.Services[idx].External = true
+			}
+		}
+		Expect(svcGraph.Validate()).To(Succeed())
+		for _, svc := range svcGraph.Services {
+			if svc.External {
+				externalPods += svc.Replicas
+			} else {
+				meshPods += svc.Replicas
+			}
+		}
+		for _, svc := range svcGraph.Services {
+			for _, edge := range svc.Edges {
+				if svcGraph.Services[edge].External {
+					caller = fakeservice.Formatters.Name(svc.Idx)
+					externalSvc = fakeservice.Formatters.Name(edge)
+				}
+			}
+		}
+		Expect(caller).ToNot(BeEmpty(), "no service calls an external service")
+
+		opts := []graph_k8s.Option{graph_k8s.WithSystemNamespace(Config.KumaNamespace), spreadAcrossNodes()}
+		if passthrough {
+			opts = append(opts, graph_k8s.WithMeshPassthrough())
+		}
+		// external services are only called on requests to the roots
+		opts = append(opts, withTraffic())
+		graphManifest = graphYaml(svcGraph, opts...)
+
+		// MeshExternalServices are reached through the ZoneEgress
+		installMeshWith([]KumaDeploymentOption{WithEgress()})
+	})
+
+	BeforeEach(func() {
+		Expect(framework.PushReportSpecMetric(cluster, obsNamespace, 1)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(framework.PushReportSpecMetric(cluster, obsNamespace, 0)).To(Succeed())
+	})
+
+	E2EAfterAll(func() {
+		Expect(cluster.DeleteNamespace(TestNamespace)).To(Succeed())
+		Expect(cluster.DeleteNamespace(graph_k8s.DefaultExternalNamespace)).To(Succeed())
+		Expect(cluster.DeleteKuma()).To(Succeed())
+	})
+
+	It("should deploy graph with external services", func() {
+		deployStart = time.Now()
+		applyGraph(graphManifest, meshPods)
+
+		Eventually(func() error {
+			return k8s.WaitUntilNumPodsCreatedE(cluster.GetTesting(), cluster.GetKubectlOptions(graph_k8s.DefaultExternalNamespace),
+				metav1.ListOptions{}, externalPods, 1, 0)
+		}, "10m", "3s").Should(Succeed())
+
+		pod := k8s.ListPods(
+			cluster.GetTesting(),
+			cluster.GetKubectlOptions(TestNamespace),
+			metav1.ListOptions{
+				LabelSelector: fmt.Sprintf("app=%s", caller),
+			},
+		)[0]
+		tnl := k8s.NewTunnel(cluster.GetKubectlOptions(TestNamespace), k8s.ResourceTypePod, pod.Name, 0, 9901)
+		Expect(tnl.ForwardPortE(cluster.GetTesting())).To(Succeed())
+		var err error
+		admin, err = tunnel.NewK8sEnvoyAdminTunnel(cluster.GetTesting(), tnl.Endpoint())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should propagate external services", func(ctx context.Context) {
+		promClient, err := framework.NewPromClient(cluster, obsNamespace)
+		Expect(err).ToNot(HaveOccurred())
+
+		waitForStableAcks(ctx, promClient)
+		AddReportEntry("external_services_stabilization_duration", time.Since(deployStart).Milliseconds())
+
+		if passthrough {
+			return
+		}
+		// a caller of an external service has a cluster for its MeshExternalService
+		Eventually(func(g Gomega) {
+			membership, err := admin.GetStats(fmt.Sprintf("cluster\\..*%s.*extsvc.*\\.membership_total", externalSvc))
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(membership.Stats).ToNot(BeEmpty())
+		}, "60s", "1s").Should(Succeed())
+	})
+
+	It("should reach external services", func() {
+		// a MeshPassthrough has a cluster per protocol and port, not per external service
+		stat := fmt.Sprintf("cluster\\..*%s.*extsvc.*\\.upstream_rq_2xx", externalSvc)
+		if passthrough {
+			stat = "cluster\\.meshpassthrough.*\\.upstream_rq_2xx"
+		}
+		Eventually(func(g Gomega) {
+			successes, err := admin.GetStats(stat)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(successes.Stats).ToNot(BeEmpty())
+			g.Expect(successes.Stats[0].Value).To(BeNumerically(">", 0))
+		}, "60s", "1s").Should(Succeed())
+	})
+}
@@ -0,0 +1,124 @@
+package k8s_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/kumahq/kuma/v2/test/framework"
+
+	graph_apis "github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/fakeservice"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/gatewayapi"
+	"github.com/kong/mesh-perf/test/framework"
+)
+
+// GatewayAPI measures the propagation of routes of the kind, GRPCRoutes are measured on a graph serving gRPC.
+func GatewayAPI(kind gatewayapi.RouteKind) func() {
+	return func() {
+		gatewayAPI(kind)
+	}
+}
+
+func gatewayAPI(kind gatewayapi.RouteKind) {
+	var svcGraph graph_apis.ServiceGraph
+	var applied int
+
+	routeOpts := func(routes int) []gatewayapi.OptionFn {
+		return []gatewayapi.OptionFn{
+			gatewayapi.WithNamespace(TestNamespace),
+			gatewayapi.WithFormatters(fakeservice.Formatters),
+			gatewayapi.WithPort(fakeservice.Port),
+			gatewayapi.WithRouteKind(kind),
+			gatewayapi.WithRoutes(routes),
+		}
+	}
+	var fakeOpts []fakeservice.OptionFn
+	if kind == gatewayapi.GRPCRoute {
+		fakeOpts = append(fakeOpts, fakeservice.WithGRPC())
+	}
+
+	BeforeAll(func() {
+		// the control plane translates Gateway API resources only when their CRDs exist on start
+		installMesh(GatewayAPICRDs)
+
+		svcGraph = graph_apis.GenerateRandomMesh(
+			872835240,
+			suiteNumServices,
+			50,
+			suiteNumInstances,
+			suiteNumInstances,
+		)
+		deployGraphWith(svcGraph, suiteNumServices*suiteNumInstances, fakeOpts, spreadAcrossNodes())
+	})
+
+	BeforeEach(func() {
+		Expect(framework.PushReportSpecMetric(cluster, obsNamespace, 1)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(framework.PushReportSpecMetric(cluster, obsNamespace, 0)).To(Succeed())
+	})
+
+	E2EAfterAll(func() {
+		Expect(cluster.DeleteNamespace(TestNamespace)).To(Succeed())
+		Expect(cluster.DeleteKuma()).To(Succeed())
+	})
+
+	for _, percent := range []int{10, 25, 50, 100} {
+		It(fmt.Sprintf("should propagate %ss of %d%% of the services", kind, percent), func(ctx context.Context) {
+			total := len(gatewayapi.Routes(svcGraph, routeOpts(-1)...))
+			routes := max(total*percent/100, 1)
+			if routes == applied {
+				Skip(fmt.Sprintf("the previous step already applied %d routes", routes))
+			}
+
+			// producer routes configure every caller of their service, only the services getting their first
+			// route change the configuration
+			routed := map[int]struct{}{}
+			all := gatewayapi.Routes(svcGraph, routeOpts(routes)...)
+			for _, route := range all[:applied] {
+				routed[route.Service] = struct{}{}
+			}
+			callers := map[int]struct{}{}
+			for _, route := range all[applied:] {
+				if _, ok := routed[route.Service]; ok {
+					continue
+				}
+				for _, svc := range svcGraph.Services {
+					for _, edge := range svc.Edges {
+						if edge == route.Service {
+							callers[svc.Idx] = struct{}{}
+						}
+					}
+				}
+			}
+			expectedAcks := 0
+			for idx := range callers {
+				expectedAcks += svcGraph.Services[idx].Replicas
+			}
+
+			promClient, err := framework.NewPromClient(cluster, obsNamespace)
+			Expect(err).ToNot(HaveOccurred())
+
+			acks := waitForStableAcks(ctx, promClient)
+			buffer := bytes.Buffer{}
+			Expect(gatewayapi.NewGenerator(routeOpts(routes)...).Apply(&buffer, svcGraph)).To(Succeed())
+			Expect(cluster.Install(YamlK8s(buffer.String()))).To(Succeed())
+			propagationStart := time.Now()
+
+			Eventually(func(g Gomega) {
+				newAcks, err := framework.XdsAckRequestsReceived(ctx, promClient)
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(newAcks - acks).To(BeNumerically(">=", expectedAcks))
+			}, "10m", "5s").Should(Succeed())
+			applied = routes
+			AddReportEntry("gateway_api_routes", routes)
+			AddReportEntry("gateway_api_route_propagation_duration", time.Since(propagationStart).Milliseconds())
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package k8s_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/kumahq/kuma/v2/test/framework"
+	"github.com/kumahq/kuma/v2/test/framework/envoy_admin"
+	"github.com/kumahq/kuma/v2/test/framework/envoy_admin/tunnel"
+
+	graph_apis "github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/gateway"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/fakeservice"
+	"github.com/kong/mesh-perf/test/framework"
+)
+
+func Gateway() {
+	const gatewayName = "edge-gateway"
+	var svcGraph graph_apis.ServiceGraph
+	var exposed int
+	var admin envoy_admin.Tunnel
+
+	gatewayYaml := func(opts ...gateway.OptionFn) string {
+		GinkgoHelper()
+
+		buffer := bytes.Buffer{}
+		generator := gateway.NewGenerator(append([]gateway.OptionFn{
+			gateway.WithName(gatewayName),
+			gateway.WithNamespace(TestNamespace),
+			gateway.WithSystemNamespace(Config.KumaNamespace),
+			gateway.WithFormatters(fakeservice.Formatters),
+			gateway.WithServicePort(fakeservice.Port),
+		}, opts...)...)
+		Expect(generator.Apply(&buffer, svcGraph)).To(Succeed())
+		return buffer.String()
+	}
+
+	BeforeAll(func() {
+		installMesh()
+
+		svcGraph = graph_apis.GenerateRandomMesh(
+			872835240,
+			suiteNumServices,
+			50,
+			suiteNumInstances,
+			suiteNumInstances,
+		)
+		deployGraph(svcGraph, suiteNumServices*suiteNumInstances, spreadAcrossNodes())
+
+		Expect(cluster.Install(YamlK8s(gatewayYaml(gateway.WithRoutes(0))))).To(Succeed())
+		Expect(cluster.Install(WaitNumPods(TestNamespace, 1, gatewayName))).To(Succeed())
+
+		pod := k8s.ListPods(
+			cluster.GetTesting(),
+			cluster.GetKubectlOptions(TestNamespace),
+			metav1.ListOptions{
+				LabelSelector: fmt.Sprintf("app=%s", gatewayName),
+			},
+		)[0]
+		tnl := k8s.NewTunnel(cluster.GetKubectlOptions(TestNamespace), k8s.ResourceTypePod, pod.Name, 0, 9901)
+		Expect(tnl.ForwardPortE(cluster.GetTesting())).To(Succeed())
+		var err error
+		admin, err = tunnel.NewK8sEnvoyAdminTunnel(cluster.GetTesting(), tnl.Endpoint())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	BeforeEach(func() {
+		Expect(framework.PushReportSpecMetric(cluster, obsNamespace, 1)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(framework.PushReportSpecMetric(cluster, obsNamespace, 0)).To(Succeed())
+	})
+
+	E2EAfterAll(func() {
+		Expect(cluster.DeleteNamespace(TestNamespace)).To(Succeed())
+		Expect(cluster.DeleteKuma()).To(Succeed())
+	})
+
+	for _, percent := range []int{10, 25, 50, 100} {
+		It(fmt.Sprintf("should propagate routes to %d%% of the roots to the gateway", percent), func() {
+			routes := max(len(svcGraph.Roots())*percent/100, 1)
+			if routes == exposed {
+				Skip(fmt.Sprintf("the previous step already exposed %d roots", routes))
+			}
+
+			var names []string
+			for _, idx := range svcGraph.Roots()[:routes] {
+				names = append(names, fakeservice.Formatters.Name(idx))
+			}
+
+			Expect(cluster.Install(YamlK8s(gatewayYaml(gateway.SkipGatewaySetup(), gateway.WithRoutes(routes))))).To(Succeed())
+			propagationStart := time.Now()
+
+			// the gateway has a cluster per root it routes to
+			Eventually(func(g Gomega) {
+				membership, err := admin.GetStats(fmt.Sprintf(
+					"cluster\\..*_(%s)_%s_.*\\.membership_total", strings.Join(names, "|"), TestNamespace,
+				))
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(membership.Stats).To(HaveLen(routes))
+			}, "10m", "1s").Should(Succeed())
+			exposed = routes
+			AddReportEntry("gateway_routes", routes)
+			AddReportEntry("gateway_route_propagation_duration", time.Since(propagationStart).Milliseconds())
+		})
+	}
+}
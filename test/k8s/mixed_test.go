@@ -0,0 +1,119 @@
+package k8s_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/kumahq/kuma/v2/test/framework"
+
+	graph_apis "github.com/kong/mesh-perf/pkg/graph/apis"
+	graph_k8s "github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/test/framework"
+)
+
+// MixedMesh runs a graph with some services without sidecars, like a mesh in the middle of a migration.
+func MixedMesh() {
+	const unmeshedPercent = 30
+	var svcGraph graph_apis.ServiceGraph
+	var meshedPods int
+
+	// count returns the value of a counter, which doesn't exist until it's incremented for the first time
+	count := func(ctx context.Context, query func(context.Context, *framework.PromClient) (int, error), promClient *framework.PromClient) int {
+		GinkgoHelper()
+
+		val, err := query(ctx, promClient)
+		if errors.Is(err, framework.ErrNoResults) {
+			return 0
+		}
+		Expect(err).ToNot(HaveOccurred())
+		return val
+	}
+
+	BeforeAll(func() {
+		installMesh()
+		// the plaintext connections of unmeshed callers are rejected by listeners of meshed callees
+		Expect(cluster.Install(YamlK8s(meshMetric("envoy_listener_(ssl_connection_error|no_filter_chain_match)")))).To(Succeed())
+
+		svcGraph = graph_apis.GenerateRandomMesh(
+			872835240,
+			suiteNumServices,
+			50,
+			suiteNumInstances,
+			suiteNumInstances,
+		)
+		for _, svc := range svcGraph.Services {
+			if !graph_k8s.Unmeshed(svc, unmeshedPercent) {
+				meshedPods += svc.Replicas
+			}
+		}
+		// services only call their upstreams on requests to the roots
+		deployGraph(
+			svcGraph,
+			suiteNumServices*suiteNumInstances,
+			graph_k8s.WithUnmeshed(unmeshedPercent),
+			withTraffic(),
+			spreadAcrossNodes(),
+		)
+	})
+
+	BeforeEach(func() {
+		Expect(framework.PushReportSpecMetric(cluster, obsNamespace, 1)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(framework.PushReportSpecMetric(cluster, obsNamespace, 0)).To(Succeed())
+	})
+
+	E2EAfterAll(func() {
+		Expect(cluster.DeleteNamespace(TestNamespace)).To(Succeed())
+		Expect(cluster.DeleteKuma()).To(Succeed())
+	})
+
+	for _, mode := range []string{"PERMISSIVE", "STRICT", "PERMISSIVE"} {
+		It(fmt.Sprintf("should switch mTLS to %s", mode), func(ctx context.Context) {
+			promClient, err := framework.NewPromClient(cluster, obsNamespace)
+			Expect(err).ToNot(HaveOccurred())
+
+			acks := waitForStableAcks(ctx, promClient)
+			requestErrors := count(ctx, framework.UpstreamRequestErrors, promClient)
+			inboundRejections := count(ctx, framework.InboundRejections, promClient)
+			connectionFailures := count(ctx, framework.UpstreamConnectionFailures, promClient)
+
+			Expect(cluster.Install(YamlK8s(fmt.Sprintf(`
+apiVersion: kuma.io/v1alpha1
+kind: Mesh
+metadata:
+  name: default
+spec:
+  meshServices:
+    mode: Exclusive
+  mtls:
+    enabledBackend: ca-1
+    backends:
+    - name: ca-1
+      type: builtin
+      mode: %s
+`, mode)))).To(Succeed())
+			propagationStart := time.Now()
+
+			// the inbounds of every meshed proxy change
+			Eventually(func(g Gomega) {
+				newAcks, err := framework.XdsAckRequestsReceived(ctx, promClient)
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(newAcks - acks).To(BeNumerically(">=", meshedPods))
+			}, "10m", "5s").Should(Succeed())
+			AddReportEntry("mtls_mode_propagation_duration", time.Since(propagationStart).Milliseconds())
+
+			waitForStableAcks(ctx, promClient)
+			AddReportEntry("unmeshed_percent", unmeshedPercent)
+			AddReportEntry("upstream_request_errors", count(ctx, framework.UpstreamRequestErrors, promClient)-requestErrors)
+			AddReportEntry("inbound_rejections", count(ctx, framework.InboundRejections, promClient)-inboundRejections)
+			AddReportEntry("upstream_connection_failures", count(ctx, framework.UpstreamConnectionFailures, promClient)-connectionFailures)
+		})
+	}
+}
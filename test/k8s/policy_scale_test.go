@@ -0,0 +1,127 @@
+package k8s_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/kumahq/kuma/v2/test/framework"
+
+	graph_apis "github.com/kong/mesh-perf/pkg/graph/apis"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/fakeservice"
+	"github.com/kong/mesh-perf/pkg/graph/generators/policies"
+	"github.com/kong/mesh-perf/test/framework"
+)
+
+// policyScalePoint is the cost of a number of targeted policies applied to the mesh.
+type policyScalePoint struct {
+	Policies              int     `json:"policies"`
+	PropagationDurationMs int64   `json:"propagationDurationMs"`
+	XdsGenerationAvgMs    float64 `json:"xdsGenerationAvgMs"`
+	CpMemoryBytes         int     `json:"cpMemoryBytes"`
+}
+
+func PolicyScale() {
+	var svcGraph graph_apis.ServiceGraph
+	var applied int
+	var curve []policyScalePoint
+
+	BeforeAll(func() {
+		installMesh()
+
+		svcGraph = graph_apis.GenerateRandomMesh(
+			872835240,
+			suiteNumServices,
+			50,
+			suiteNumInstances,
+			suiteNumInstances,
+		)
+		deployGraph(svcGraph, suiteNumServices*suiteNumInstances)
+	})
+
+	BeforeEach(func() {
+		Expect(framework.PushReportSpecMetric(cluster, obsNamespace, 1)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(framework.PushReportSpecMetric(cluster, obsNamespace, 0)).To(Succeed())
+	})
+
+	E2EAfterAll(func() {
+		Expect(cluster.DeleteNamespace(TestNamespace)).To(Succeed())
+		Expect(cluster.DeleteKuma()).To(Succeed())
+	})
+
+	for _, total := range []int{10, 100, 1000} {
+		It(fmt.Sprintf("should propagate %d targeted policies", total), func(ctx context.Context) {
+			promClient, err := framework.NewPromClient(cluster, obsNamespace)
+			Expect(err).ToNot(HaveOccurred())
+
+			acks := waitForStableAcks(ctx, promClient)
+			generationSum, err := framework.XdsGenerationSum(ctx, promClient)
+			Expect(err).ToNot(HaveOccurred())
+			generationCount, err := framework.XdsGenerationCount(ctx, promClient)
+			Expect(err).ToNot(HaveOccurred())
+
+			// every proxy of the services configured by the new batch gets a new config
+			expectedAcks := 0
+			for _, idx := range policies.TargetedServices(svcGraph, applied, total) {
+				expectedAcks += svcGraph.Services[idx].Replicas
+			}
+
+			buffer := bytes.Buffer{}
+			generator := policies.NewGenerator(
+				policies.WithNamespace(Config.KumaNamespace),
+				policies.WithServiceNamespace(TestNamespace),
+				policies.WithFormatters(fakeservice.Formatters),
+				policies.WithTargeted(policies.DefaultTimeout, applied, total),
+			)
+			Expect(generator.Apply(&buffer, svcGraph)).To(Succeed())
+			Expect(cluster.Install(YamlK8s(buffer.String()))).To(Succeed())
+			propagationStart := time.Now()
+
+			Eventually(func(g Gomega) {
+				newAcks, err := framework.XdsAckRequestsReceived(ctx, promClient)
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(newAcks - acks).To(BeNumerically(">=", expectedAcks))
+			}, "10m", "5s").Should(Succeed())
+			propagationDuration := time.Since(propagationStart).Milliseconds()
+			applied = total
+
+			waitForStableAcks(ctx, promClient)
+			newGenerationSum, err := framework.XdsGenerationSum(ctx, promClient)
+			Expect(err).ToNot(HaveOccurred())
+			newGenerationCount, err := framework.XdsGenerationCount(ctx, promClient)
+			Expect(err).ToNot(HaveOccurred())
+			memory, err := framework.CpMemoryWorkingSetBytes(ctx, promClient, Config.KumaNamespace)
+			Expect(err).ToNot(HaveOccurred())
+
+			point := policyScalePoint{
+				Policies:              total,
+				PropagationDurationMs: propagationDuration,
+				CpMemoryBytes:         memory,
+			}
+			if generations := newGenerationCount - generationCount; generations > 0 {
+				point.XdsGenerationAvgMs = (newGenerationSum - generationSum) / float64(generations)
+			}
+			curve = append(curve, point)
+
+			AddReportEntry("policy_count", point.Policies)
+			AddReportEntry("policy_propagation_duration", point.PropagationDurationMs)
+			AddReportEntry("xds_generation_avg_duration", point.XdsGenerationAvgMs)
+			AddReportEntry("cp_memory_working_set_bytes", point.CpMemoryBytes)
+		})
+	}
+
+	It("should report the cost of policies", func() {
+		Expect(curve).ToNot(BeEmpty())
+		out, err := json.Marshal(curve)
+		Expect(err).ToNot(HaveOccurred())
+		AddReportEntry("policy_scale_curve", string(out))
+	})
+}
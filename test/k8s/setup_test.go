@@ -0,0 +1,277 @@
+package k8s_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	mesh "github.com/kumahq/kuma/v2/api/mesh/v1alpha1"
+	"github.com/kumahq/kuma/v2/pkg/config/core"
+	"github.com/kumahq/kuma/v2/pkg/test/resources/builders"
+	. "github.com/kumahq/kuma/v2/test/framework"
+
+	graph_apis "github.com/kong/mesh-perf/pkg/graph/apis"
+	graph_k8s "github.com/kong/mesh-perf/pkg/graph/generators/k8s"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/fakeservice"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/validate"
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/workloadapp"
+	"github.com/kong/mesh-perf/pkg/graph/generators/policies"
+	"github.com/kong/mesh-perf/test/framework"
+)
+
+// installMesh installs the control plane, the test namespace with sidecar injection and
+// the default mesh with mTLS, MeshServices, Prometheus metrics and all the traffic allowed. Prerequisites of the
+// control plane, like CRDs it watches, are installed before it.
+func installMesh(prerequisites ...InstallFunc) {
+	GinkgoHelper()
+
+	installMeshWith(nil, prerequisites...)
+}
+
+// installMeshWith is installMesh with extra options of the control plane, like a ZoneEgress.
+func installMeshWith(kumaOpts []KumaDeploymentOption, prerequisites ...InstallFunc) {
+	GinkgoHelper()
+
+	opts := []KumaDeploymentOption{
+		WithSkipDefaultMesh(true),
+		WithCtlOpts(map[string]string{
+			"--set": strings.Join([]string{
+				"kuma.controlPlane.resources.requests.cpu=1",
+				"kuma.controlPlane.resources.requests.memory=2Gi",
+				"kuma.controlPlane.resources.limits.memory=32Gi",
+			}, ","),
+			"--env-var": strings.Join([]string{
+				"KUMA_RUNTIME_KUBERNETES_LEADER_ELECTION_LEASE_DURATION=100s",
+				"KUMA_RUNTIME_KUBERNETES_LEADER_ELECTION_RENEW_DEADLINE=80s",
+				fmt.Sprintf("KUMA_DIAGNOSTICS_DEBUG_ENDPOINTS=%v", debug),
+			}, ","),
+		}),
+	}
+
+	if containerRegistry != "" {
+		opts = append(opts,
+			WithCtlOpts(map[string]string{
+				"--dataplane-registry": containerRegistry,
+			}))
+	}
+
+	opts = append(opts,
+		WithCtlOpts(map[string]string{
+			"--license-path": kmeshLicense,
+		}))
+
+	opts = append(opts, kumaOpts...)
+
+	setup := NewClusterSetup()
+	for _, prerequisite := range prerequisites {
+		setup = setup.Install(prerequisite)
+	}
+	err := setup.
+		Install(Kuma(core.Zone, opts...)).
+		Install(NamespaceWithSidecarInjection(TestNamespace)).
+		Setup(cluster)
+	Expect(err).ToNot(HaveOccurred())
+
+	Expect(cluster.Install(YamlK8s(builders.
+		Mesh().
+		WithMeshServicesEnabled(mesh.Mesh_MeshServices_Exclusive).
+		WithBuiltinMTLSBackend("ca-1").
+		WithEnabledMTLSBackend("ca-1").
+		WithoutInitialPolicies().
+		KubeYaml(),
+	))).To(Succeed())
+
+	// the mesh has mTLS and no initial policies, without it the requests of the load generator and of the
+	// services to their upstreams are denied
+	allowAll := bytes.Buffer{}
+	Expect(policies.NewGenerator(
+		policies.WithNamespace(Config.KumaNamespace),
+		policies.WithAllowAll(),
+	).Apply(&allowAll, graph_apis.ServiceGraph{})).To(Succeed())
+	Expect(cluster.Install(YamlK8s(allowAll.String()))).To(Succeed())
+
+	Expect(cluster.Install(YamlK8s(meshMetric()))).To(Succeed())
+}
+
+// meshMetric is the MeshMetric of the mesh, scraped by Prometheus, with the basic stats of the sidecars and the
+// ones matching the regexes.
+func meshMetric(include ...string) string {
+	var includes strings.Builder
+	if len(include) > 0 {
+		includes.WriteString("        include:\n")
+	}
+	for _, regex := range include {
+		fmt.Fprintf(&includes, "        - type: Regex\n          match: %q\n", regex)
+	}
+	return `
+apiVersion: kuma.io/v1alpha1
+kind: MeshMetric
+metadata:
+  name: default
+  namespace: kong-mesh-system
+spec:
+  default:
+    backends:
+    - type: Prometheus
+      prometheus:
+        port: 5670
+        path: "/metrics"
+    sidecar:
+      profiles:
+        appendProfiles:
+        - name: Basic
+` + includes.String()
+}
+
+// spreadAcrossNodes spreads the pods of the whole graph evenly across the nodes, like eksformula sizes the node
+// pool for. Scenarios opt in, so the placement of the ones with earlier results stays comparable.
+func spreadAcrossNodes() graph_k8s.Option {
+	return graph_k8s.WithPlacement(graph_k8s.Placement{
+		TopologySpread: []graph_k8s.TopologySpread{
+			{TopologyKey: "kubernetes.io/hostname", MaxSkew: 1, ScheduleAnyway: true, AcrossGraph: true},
+		},
+	})
+}
+
+// withTraffic deploys a load generator with the graph, even when the suite runs without one, for scenarios that
+// check how requests go.
+func withTraffic() graph_k8s.Option {
+	rps := loadGeneratorRPS
+	if rps <= 0 {
+		rps = 1
+	}
+	return workloadapp.WithLoadGenerator(containerRegistry, rps)
+}
+
+// deployGraph deploys the fake services of the graph to the test namespace and waits for their pods, the load
+// generator aside. With a load generator, it waits for its requests to succeed too, so the disruption measured
+// later isn't the one of a load generator that can't reach the graph.
+func deployGraph(svcGraph graph_apis.ServiceGraph, expectedNumOfPods int, opts ...graph_k8s.Option) {
+	GinkgoHelper()
+
+	deployGraphWith(svcGraph, expectedNumOfPods, nil, opts...)
+}
+
+// deployGraphWith is deployGraph with extra options of the fake services, like their protocol.
+func deployGraphWith(svcGraph graph_apis.ServiceGraph, expectedNumOfPods int, fakeOpts []fakeservice.OptionFn, opts ...graph_k8s.Option) {
+	GinkgoHelper()
+
+	applyGraph(graphYamlWith(svcGraph, fakeOpts, opts...), expectedNumOfPods)
+}
+
+// applyGraph applies the manifest of a graph generated up front with graphYaml and waits like deployGraph.
+func applyGraph(manifest string, expectedNumOfPods int) {
+	GinkgoHelper()
+
+	Expect(cluster.Install(YamlK8s(manifest))).To(Succeed())
+
+	// the pod of the load generator isn't a pod of the graph
+	graphPods := metav1.ListOptions{LabelSelector: "app!=" + graph_k8s.LoadGeneratorName}
+	Eventually(func() error {
+		return k8s.WaitUntilNumPodsCreatedE(cluster.GetTesting(), cluster.GetKubectlOptions(TestNamespace),
+			graphPods, expectedNumOfPods, 1, 0)
+	}, "10m", "3s").Should(Succeed())
+
+	if loadGeneratorRPS <= 0 {
+		return
+	}
+	promClient, err := framework.NewPromClient(cluster, obsNamespace)
+	Expect(err).ToNot(HaveOccurred())
+	Eventually(func(g Gomega) {
+		successes, err := framework.LoadGeneratorSuccesses(context.Background(), promClient)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(successes).To(BeNumerically(">", 0), "the load generator gets no 2xx responses")
+	}, "5m", "5s").Should(Succeed())
+}
+
+// graphYaml generates the fake services of the graph in the test namespace and validates them offline. Scenarios
+// generate their manifests in BeforeAll, so one kubectl would reject fails before the mesh is measured.
+func graphYaml(svcGraph graph_apis.ServiceGraph, opts ...graph_k8s.Option) string {
+	GinkgoHelper()
+
+	return graphYamlWith(svcGraph, nil, opts...)
+}
+
+// graphYamlWith is graphYaml with extra options of the fake services.
+func graphYamlWith(svcGraph graph_apis.ServiceGraph, fakeOpts []fakeservice.OptionFn, opts ...graph_k8s.Option) string {
+	GinkgoHelper()
+
+	buffer := bytes.Buffer{}
+	opts = append(
+		append(
+			fakeservice.GeneratorOpts(append([]fakeservice.OptionFn{
+				fakeservice.WithRegistry(containerRegistry),
+				fakeservice.WithReachableBackends(),
+				fakeservice.WithSystemNamespace(Config.KumaNamespace),
+			}, fakeOpts...)...),
+			graph_k8s.WithNamespace(TestNamespace),
+			graph_k8s.SkipNamespaceCreation(),
+		),
+		opts...,
+	)
+	if loadGeneratorRPS > 0 {
+		opts = append(opts, workloadapp.WithLoadGenerator(containerRegistry, loadGeneratorRPS))
+	}
+
+	generator, err := graph_k8s.NewGenerator(opts...)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(generator.Apply(&buffer, svcGraph)).To(Succeed())
+	Expect(validate.Manifest(buffer.Bytes())).To(Succeed())
+	Expect(validate.References(buffer.Bytes())).To(Succeed())
+	return buffer.String()
+}
+
+// disruptionSampleInterval is how often the failed requests of the load generator are sampled.
+const disruptionSampleInterval = 5 * time.Second
+
+// measureDisruption samples the requests of the load generator from now until the end of the spec, its
+// stabilization included, and reports how they were disrupted. It does nothing without a load generator.
+func measureDisruption() {
+	GinkgoHelper()
+
+	if loadGeneratorRPS <= 0 {
+		return
+	}
+	promClient, err := framework.NewPromClient(cluster, obsNamespace)
+	Expect(err).ToNot(HaveOccurred())
+	sampler, err := framework.StartDisruptionSampler(promClient, disruptionSampleInterval)
+	Expect(err).ToNot(HaveOccurred())
+	// cleanups of a spec run after its AfterEach
+	DeferCleanup(func() {
+		disruption, err := sampler.Stop()
+		Expect(err).ToNot(HaveOccurred())
+		disruption.AddReportEntries()
+	})
+}
+
+// expectedProxies is the number of proxies of the pods of the graph, with the sidecar of the load generator when
+// it's deployed. The graphs of the suite are served over HTTP, so the load generator always has roots to call.
+func expectedProxies(graphPods int) int {
+	if loadGeneratorRPS > 0 {
+		return graphPods + 1
+	}
+	return graphPods
+}
+
+// waitForStableAcks waits until the number of xDS ACKs stops changing and returns it.
+func waitForStableAcks(ctx context.Context, promClient *framework.PromClient) int {
+	GinkgoHelper()
+
+	var acks int
+	Eventually(func(g Gomega) {
+		newAcks, err := framework.XdsAckRequestsReceived(ctx, promClient)
+		g.Expect(err).ToNot(HaveOccurred())
+		if acks != newAcks {
+			acks = newAcks
+			g.Expect(true).To(BeFalse(), "acks are not stable")
+		}
+	}, "10m", "5s").MustPassRepeatedly(7).Should(Succeed())
+	return acks
+}
@@ -1,10 +1,8 @@
 package k8s_test
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/gruntwork-io/terratest/modules/k8s"
@@ -12,15 +10,11 @@ import (
 	. "github.com/onsi/gomega"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	mesh "github.com/kumahq/kuma/v2/api/mesh/v1alpha1"
-	"github.com/kumahq/kuma/v2/pkg/config/core"
-	"github.com/kumahq/kuma/v2/pkg/test/resources/builders"
 	. "github.com/kumahq/kuma/v2/test/framework"
 	"github.com/kumahq/kuma/v2/test/framework/envoy_admin"
 	"github.com/kumahq/kuma/v2/test/framework/envoy_admin/tunnel"
 
 	graph_apis "github.com/kong/mesh-perf/pkg/graph/apis"
-	graph_k8s "github.com/kong/mesh-perf/pkg/graph/generators/k8s"
 	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/fakeservice"
 	"github.com/kong/mesh-perf/test/framework"
 )
@@ -28,70 +22,9 @@ import (
 func Simple() {
 	var start time.Time
 	var svcGraph graph_apis.ServiceGraph
+	var graphManifest string
 
 	BeforeAll(func() {
-		opts := []KumaDeploymentOption{
-			WithSkipDefaultMesh(true),
-			WithCtlOpts(map[string]string{
-				"--set": strings.Join([]string{
-					"kuma.controlPlane.resources.requests.cpu=1",
-					"kuma.controlPlane.resources.requests.memory=2Gi",
-					"kuma.controlPlane.resources.limits.memory=32Gi",
-				}, ","),
-				"--env-var": strings.Join([]string{
-					"KUMA_RUNTIME_KUBERNETES_LEADER_ELECTION_LEASE_DURATION=100s",
-					"KUMA_RUNTIME_KUBERNETES_LEADER_ELECTION_RENEW_DEADLINE=80s",
-					fmt.Sprintf("KUMA_DIAGNOSTICS_DEBUG_ENDPOINTS=%v", debug),
-				}, ","),
-			}),
-		}
-
-		if containerRegistry != "" {
-			opts = append(opts,
-				WithCtlOpts(map[string]string{
-					"--dataplane-registry": containerRegistry,
-				}))
-		}
-
-		opts = append(opts,
-			WithCtlOpts(map[string]string{
-				"--license-path": kmeshLicense,
-			}))
-
-		err := NewClusterSetup().
-			Install(Kuma(core.Zone, opts...)).
-			Install(NamespaceWithSidecarInjection(TestNamespace)).
-			Setup(cluster)
-		Expect(err).ToNot(HaveOccurred())
-
-		Expect(cluster.Install(YamlK8s(builders.
-			Mesh().
-			WithMeshServicesEnabled(mesh.Mesh_MeshServices_Exclusive).
-			WithBuiltinMTLSBackend("ca-1").
-			WithEnabledMTLSBackend("ca-1").
-			WithoutInitialPolicies().
-			KubeYaml(),
-		))).To(Succeed())
-
-		Expect(cluster.Install(YamlK8s(`
-apiVersion: kuma.io/v1alpha1
-kind: MeshMetric
-metadata:
-  name: default
-  namespace: kong-mesh-system
-spec:
-  default:
-    backends:
-    - type: Prometheus
-      prometheus:
-        port: 5670
-        path: "/metrics"
-    sidecar:
-      profiles:
-        appendProfiles:
-        - name: Basic
-`))).To(Succeed())
-
 		svcGraph = graph_apis.GenerateRandomMesh(
 			872835240,
 			suiteNumServices,
@@ -99,6 +32,9 @@ spec:
 			suiteNumInstances,
 			suiteNumInstances,
 		)
+		graphManifest = graphYaml(svcGraph)
+
+		installMesh()
 	})
 
 	BeforeEach(func() {
@@ -144,41 +80,16 @@ spec:
 	})
 
 	It("should deploy graph", func() {
-		buffer := bytes.Buffer{}
-		opts := append(
-			fakeservice.GeneratorOpts(
-				fakeservice.WithRegistry(containerRegistry),
-				fakeservice.WithReachableBackends(),
-			),
-			graph_k8s.WithNamespace(TestNamespace),
-			graph_k8s.SkipNamespaceCreation(),
-		)
-
-		generator, err := graph_k8s.NewGenerator(opts...)
-		Expect(err).ToNot(HaveOccurred())
-		Expect(generator.Apply(&buffer, svcGraph)).To(Succeed())
-		Expect(cluster.Install(YamlK8s(buffer.String()))).To(Succeed())
-
-		Eventually(func() error {
-			expectedNumOfPods := suiteNumServices * suiteNumInstances
-			return k8s.WaitUntilNumPodsCreatedE(cluster.GetTesting(), cluster.GetKubectlOptions(TestNamespace),
-				metav1.ListOptions{}, expectedNumOfPods, 1, 0)
-		}, "10m", "3s").Should(Succeed())
+		applyGraph(graphManifest, suiteNumServices*suiteNumInstances)
 	})
 
 	It("should deploy mesh wide policy", func(ctx context.Context) {
+		measureDisruption()
+
 		promClient, err := framework.NewPromClient(cluster, obsNamespace)
 		Expect(err).ToNot(HaveOccurred())
 
-		var acks int
-		Eventually(func(g Gomega) {
-			newAcks, err := framework.XdsAckRequestsReceived(ctx, promClient)
-			g.Expect(err).ToNot(HaveOccurred())
-			if acks != newAcks {
-				acks = newAcks
-				g.Expect(true).To(BeFalse(), "acks are not stable")
-			}
-		}, "10m", "5s").MustPassRepeatedly(7).Should(Succeed())
+		acks := waitForStableAcks(ctx, promClient)
 
 		policy := `
 apiVersion: kuma.io/v1alpha1
@@ -209,7 +120,7 @@ spec:
 		Eventually(func(g Gomega) {
 			newAcks, err := framework.XdsAckRequestsReceived(ctx, promClient)
 			g.Expect(err).ToNot(HaveOccurred())
-			g.Expect(newAcks - acks).To(Equal(suiteNumServices * suiteNumInstances))
+			g.Expect(newAcks - acks).To(Equal(expectedProxies(suiteNumServices * suiteNumInstances)))
 		}, "10m", "5s").Should(Succeed())
 		AddReportEntry("policy_propagation_duration", time.Since(propagationStart).Milliseconds())
 	})
@@ -244,6 +155,8 @@ spec:
 		})
 
 		scale := func(replicas int) {
+			measureDisruption()
+
 			err := k8s.RunKubectlE(
 				cluster.GetTesting(),
 				cluster.GetKubectlOptions(TestNamespace),
@@ -274,7 +187,9 @@ spec:
 	})
 
 	It("should distribute certs when mTLS is enabled", func() {
-		expectedCerts := suiteNumServices * suiteNumInstances
+		measureDisruption()
+
+		expectedCerts := expectedProxies(suiteNumServices * suiteNumInstances)
 		// Step 1: Add ca-2 backend while keeping ca-1 enabled
 		Expect(cluster.Install(YamlK8s(`
 apiVersion: kuma.io/v1alpha1
@@ -20,6 +20,7 @@ import (
 	. "github.com/kumahq/kuma/v2/test/framework"
 	obs "github.com/kumahq/kuma/v2/test/framework/deployments/observability"
 
+	"github.com/kong/mesh-perf/pkg/graph/generators/k8s/gatewayapi"
 	"github.com/kong/mesh-perf/test/framework"
 )
 
@@ -46,6 +47,7 @@ var (
 	suiteNumInstances  int
 	kmeshLicense       string
 	containerRegistry  string
+	loadGeneratorRPS   float64
 	debug              bool
 )
 
@@ -70,6 +72,12 @@ var _ = BeforeSuite(func() {
 
 	containerRegistry = os.Getenv("CONTAINER_REGISTRY")
 
+	if v := os.Getenv("PERF_TEST_LOAD_GENERATOR_RPS"); v != "" {
+		var err error
+		loadGeneratorRPS, err = strconv.ParseFloat(v, 64)
+		Expect(err).ToNot(HaveOccurred(), "invalid value of PERF_TEST_LOAD_GENERATOR_RPS")
+	}
+
 	kmeshLicense = requireVar("KMESH_LICENSE")
 	sleep := requireVar("PERF_TEST_STABILIZATION_SLEEP")
 	sleepDur, err := time.ParseDuration(sleep)
@@ -157,4 +165,12 @@ var _ = ReportAfterSuite("compile report", func(ginkgoReport Report) {
 var (
 	_ = Describe("Simple", Simple, Ordered)
 	_ = Describe("ResourceLimits", Label("limits"), ResourceLimits, Ordered)
+	_ = Describe("PolicyScale", Label("policies"), PolicyScale, Ordered)
+	_ = Describe("Canary", Label("routes"), Canary, Ordered)
+	_ = Describe("Gateway", Label("routes"), Gateway, Ordered)
+	_ = Describe("GatewayAPI", Label("routes"), GatewayAPI(gatewayapi.HTTPRoute), Ordered)
+	_ = Describe("GatewayAPIGRPC", Label("routes"), GatewayAPI(gatewayapi.GRPCRoute), Ordered)
+	_ = Describe("External", Label("external"), External(false), Ordered)
+	_ = Describe("ExternalPassthrough", Label("external"), External(true), Ordered)
+	_ = Describe("MixedMesh", Label("mixed"), MixedMesh, Ordered)
 )
@@ -5,6 +5,8 @@ import (
 	"math"
 	"os"
 	"strconv"
+
+	graph_k8s "github.com/kong/mesh-perf/pkg/graph/generators/k8s"
 )
 
 func main() {
@@ -23,12 +25,18 @@ func main() {
 	// for 2 additional pods per node in our calculation.
 	extrasPerNode := 2
 
-	// We're using the 't4g.2xlarge' instance type, which can run up to 58 pods per node.
+	// We're using the 't4g.2xlarge' instance type (8 vCPUs), which can run up to 58 pods per node.
 	// See the full list of instance types and their pod limits here:
 	// https://github.com/awslabs/amazon-eks-ami/blob/master/files/eni-max-pods.txt
-	// Each application pod along with its kuma-dp sidecar requests 150m CPU.
-	// This means a maximum of 53 application pods can run on a single node.
-	podsPerNode := 53
+	// Each application pod along with its kuma-dp sidecar requests the CPU of the default
+	// resource profile of the generated manifests: the app container and the sidecar request set by
+	// the kuma.io/sidecar-proxy-cpu-requests annotation, or the control plane default without one
+	// (150m by default, which gives 53 pods per node).
+	// Scenarios spreading the pods of the whole graph across the nodes fill the nodes evenly.
+	nodeMilliCPU := int64(8000)
+	maxPodsPerNode := 58
+	podMilliCPU := graph_k8s.DefaultResourceProfile().PodCPURequest()
+	podsPerNode := min(maxPodsPerNode, int(nodeMilliCPU/podMilliCPU.MilliValue()))
 
 	fmt.Print(math.Ceil(float64(services*instancesPerService+extras) / float64(podsPerNode-extrasPerNode)))
 }
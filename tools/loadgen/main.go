@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kong/mesh-perf/pkg/loadgen"
+)
+
+func main() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	config, err := loadgen.ConfigFromEnv(os.LookupEnv)
+	if err != nil {
+		logger.Error("invalid config", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("sending load", "targets", config.Targets, "rps", config.RPS, "address", config.ListenAddr)
+	if err := loadgen.New(config).ListenAndServe(ctx); err != nil {
+		logger.Error("failed serving", "error", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kong/mesh-perf/pkg/workload"
+)
+
+// configFileInterval is how often the config file is checked for changes, the kubelet updates
+// mounted ConfigMaps about every minute.
+const configFileInterval = 5 * time.Second
+
+func main() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	slog.SetDefault(logger)
+
+	configFile := os.Getenv(workload.ConfigFileEnv)
+	config, err := readConfig(configFile)
+	if err != nil {
+		logger.Error("invalid config", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client, err := workload.NewClient(config)
+	if err != nil {
+		logger.Error("invalid client config", "error", err)
+		os.Exit(1)
+	}
+	server := workload.New(config, workload.WithClient(client))
+	if configFile != "" {
+		go server.WatchConfigFile(ctx, configFile, os.LookupEnv, configFileInterval)
+	}
+	logger.Info("listening", "name", config.Name, "address", config.ListenAddr, "upstreams", config.Upstreams)
+	if err := server.ListenAndServe(ctx); err != nil {
+		logger.Error("failed serving", "error", err)
+		os.Exit(1)
+	}
+}
+
+func readConfig(configFile string) (workload.Config, error) {
+	if configFile == "" {
+		return workload.ConfigFromEnv(os.LookupEnv)
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return workload.Config{}, err
+	}
+	return workload.ConfigFromFile(data, os.LookupEnv)
+}